@@ -0,0 +1,140 @@
+package servertransport
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/juju/errors"
+)
+
+// DefaultMaxFrameSize is used by codecs that enforce a frame size limit when
+// no explicit limit is configured.
+const DefaultMaxFrameSize = 1 << 20 // 1 MiB
+
+// MetadataCodec encodes and decodes metadataEvent values to and from a
+// stream. Implementations decide how message boundaries are represented on
+// the wire, which lets MetadataTransport work over both packet-oriented and
+// stream-oriented conns.
+type MetadataCodec interface {
+	Encode(w io.Writer, event metadataEvent) error
+	Decode(r io.Reader) (metadataEvent, error)
+}
+
+// lengthPrefixedCodec frames each metadataEvent with a 4-byte big-endian
+// length prefix followed by the JSON-encoded payload. Use it for
+// stream-oriented conns (TCP, muxed streams) where a single Read is not
+// guaranteed to return a whole message.
+type lengthPrefixedCodec struct {
+	maxFrameSize uint32
+}
+
+// NewLengthPrefixedCodec returns a MetadataCodec suitable for stream conns.
+// A maxFrameSize of 0 defaults to DefaultMaxFrameSize.
+func NewLengthPrefixedCodec(maxFrameSize uint32) MetadataCodec {
+	if maxFrameSize == 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+
+	return &lengthPrefixedCodec{maxFrameSize: maxFrameSize}
+}
+
+func (c *lengthPrefixedCodec) Encode(w io.Writer, event metadataEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if uint32(len(b)) > c.maxFrameSize {
+		return errors.Errorf("encode: frame too large: %d > %d", len(b), c.maxFrameSize)
+	}
+
+	var header [4]byte
+
+	binary.BigEndian.PutUint32(header[:], uint32(len(b)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return errors.Annotatef(err, "encode: write header")
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return errors.Annotatef(err, "encode: write payload")
+	}
+
+	return nil
+}
+
+func (c *lengthPrefixedCodec) Decode(r io.Reader) (metadataEvent, error) {
+	var event metadataEvent
+
+	var header [4]byte
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return event, errors.Annotatef(err, "decode: read header")
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+
+	if size > c.maxFrameSize {
+		return event, errors.Errorf("decode: frame too large: %d > %d", size, c.maxFrameSize)
+	}
+
+	payload := make([]byte, size)
+
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return event, errors.Annotatef(err, "decode: read payload")
+	}
+
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return event, errors.Annotatef(err, "decode: unmarshal")
+	}
+
+	return event, nil
+}
+
+// datagramCodec encodes a metadataEvent as a single JSON message per Write,
+// and expects a single Read to return exactly one message. This is the
+// historical behavior of MetadataTransport and only works correctly over
+// packet-oriented conns that never split or coalesce messages across reads.
+type datagramCodec struct {
+	receiveMTU int
+}
+
+// NewDatagramCodec returns a MetadataCodec that preserves the
+// single-read-per-message behavior used by packet conns. A receiveMTU of 0
+// defaults to ReceiveMTU.
+func NewDatagramCodec(receiveMTU int) MetadataCodec {
+	if receiveMTU == 0 {
+		receiveMTU = ReceiveMTU
+	}
+
+	return &datagramCodec{receiveMTU: receiveMTU}
+}
+
+func (c *datagramCodec) Encode(w io.Writer, event metadataEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	_, err = w.Write(b)
+
+	return errors.Trace(err)
+}
+
+func (c *datagramCodec) Decode(r io.Reader) (metadataEvent, error) {
+	var event metadataEvent
+
+	buf := make([]byte, c.receiveMTU)
+
+	i, err := r.Read(buf)
+	if err != nil {
+		return event, errors.Annotatef(err, "decode: read")
+	}
+
+	if err := json.Unmarshal(buf[:i], &event); err != nil {
+		return event, errors.Annotatef(err, "decode: unmarshal")
+	}
+
+	return event, nil
+}
@@ -0,0 +1,307 @@
+package servertransport
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/peer-calls/peer-calls/server/servertransport/proto"
+	"github.com/peer-calls/peer-calls/server/transport"
+)
+
+// protoCodec frames events with the same 4-byte big-endian length prefix as
+// lengthPrefixedCodec, but encodes the payload as a proto.Event instead of
+// JSON. Like the JSON codec, it receives tracks already converted by
+// toSimpleTrack, so any transport.Track implementation can be sent; track
+// fields are written onto the typed fields of proto.TrackInfo.
+type protoCodec struct {
+	maxFrameSize uint32
+}
+
+// NewProtoCodec returns a MetadataCodec that encodes events as protobuf. A
+// maxFrameSize of 0 defaults to DefaultMaxFrameSize.
+func NewProtoCodec(maxFrameSize uint32) MetadataCodec {
+	if maxFrameSize == 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+
+	return &protoCodec{maxFrameSize: maxFrameSize}
+}
+
+func (c *protoCodec) Encode(w io.Writer, event metadataEvent) error {
+	pbEvent, err := newEventProto(event)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	b, err := pbEvent.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if uint32(len(b)) > c.maxFrameSize {
+		return errors.Errorf("encode: frame too large: %d > %d", len(b), c.maxFrameSize)
+	}
+
+	var header [4]byte
+
+	binary.BigEndian.PutUint32(header[:], uint32(len(b)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return errors.Annotatef(err, "encode: write header")
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return errors.Annotatef(err, "encode: write payload")
+	}
+
+	return nil
+}
+
+func (c *protoCodec) Decode(r io.Reader) (metadataEvent, error) {
+	var event metadataEvent
+
+	var header [4]byte
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return event, errors.Annotatef(err, "decode: read header")
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+
+	if size > c.maxFrameSize {
+		return event, errors.Errorf("decode: frame too large: %d > %d", size, c.maxFrameSize)
+	}
+
+	payload := make([]byte, size)
+
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return event, errors.Annotatef(err, "decode: read payload")
+	}
+
+	var pbEvent proto.Event
+
+	if err := pbEvent.Unmarshal(payload); err != nil {
+		return event, errors.Annotatef(err, "decode: unmarshal")
+	}
+
+	event, err := eventFromProto(pbEvent)
+
+	return event, errors.Trace(err)
+}
+
+// trackFields is the subset of a transport.Track implementation protoCodec
+// needs to describe a track on the wire beyond its UniqueID. Earlier code
+// downcast straight to transport.SimpleTrack to read these; asserting this
+// narrower interface instead means any transport.Track implementation can be
+// sent, just with fewer fields populated if it doesn't implement them.
+type trackFields interface {
+	StreamID() string
+	RID() string
+	SSRC() uint32
+	PayloadType() uint8
+}
+
+func newEventProto(event metadataEvent) (*proto.Event, error) {
+	switch event.Type {
+	case metadataEventTypeTrack:
+		return &proto.Event{
+			Payload: &proto.Event_TrackEvent{TrackEvent: newTrackEventProto(*event.Track)},
+		}, nil
+	case metadataEventTypeInit:
+		return &proto.Event{
+			Payload: &proto.Event_InitEvent{InitEvent: &proto.InitEvent{
+				ClientId:        event.Init.ClientID,
+				ProtocolVersion: uint32(event.Init.ProtocolVersion),
+				Capabilities:    event.Init.Capabilities,
+			}},
+		}, nil
+	case metadataEventTypeBye:
+		return &proto.Event{Payload: &proto.Event_ByeEvent{ByeEvent: &proto.ByeEvent{}}}, nil
+	case metadataEventTypePLI:
+		return &proto.Event{Payload: &proto.Event_PliEvent{PliEvent: newFeedbackEventProto(*event.Feedback)}}, nil
+	case metadataEventTypeFIR:
+		return &proto.Event{Payload: &proto.Event_FirEvent{FirEvent: newFeedbackEventProto(*event.Feedback)}}, nil
+	case metadataEventTypeREMB:
+		return &proto.Event{Payload: &proto.Event_RembEvent{RembEvent: newFeedbackEventProto(*event.Feedback)}}, nil
+	case metadataEventTypeNACK:
+		return &proto.Event{Payload: &proto.Event_NackEvent{NackEvent: newFeedbackEventProto(*event.Feedback)}}, nil
+	case metadataEventTypeSenderReport:
+		return &proto.Event{Payload: &proto.Event_SenderReportEvent{SenderReportEvent: newFeedbackEventProto(*event.Feedback)}}, nil
+	case metadataEventTypePing:
+		return &proto.Event{Payload: &proto.Event_PingEvent{PingEvent: &proto.PingEvent{Nonce: event.Ping.Nonce}}}, nil
+	case metadataEventTypePong:
+		return &proto.Event{Payload: &proto.Event_PongEvent{PongEvent: &proto.PingEvent{Nonce: event.Ping.Nonce}}}, nil
+	default:
+		return nil, errors.Errorf("newEventProto: unknown metadata event type: %s", event.Type)
+	}
+}
+
+func eventFromProto(pbEvent proto.Event) (metadataEvent, error) {
+	switch p := pbEvent.Payload.(type) {
+	case *proto.Event_TrackEvent:
+		trackEvent := trackEventFromProto(p.TrackEvent)
+
+		return metadataEvent{Type: metadataEventTypeTrack, Track: &trackEvent}, nil
+	case *proto.Event_InitEvent:
+		return metadataEvent{
+			Type: metadataEventTypeInit,
+			Init: &initEventJSON{
+				ClientID:        p.InitEvent.ClientId,
+				ProtocolVersion: int(p.InitEvent.ProtocolVersion),
+				Capabilities:    p.InitEvent.Capabilities,
+			},
+		}, nil
+	case *proto.Event_ByeEvent:
+		return metadataEvent{Type: metadataEventTypeBye, Bye: &byeEventJSON{}}, nil
+	case *proto.Event_PliEvent:
+		fb := feedbackEventFromProto(p.PliEvent)
+
+		return metadataEvent{Type: metadataEventTypePLI, Feedback: &fb}, nil
+	case *proto.Event_FirEvent:
+		fb := feedbackEventFromProto(p.FirEvent)
+
+		return metadataEvent{Type: metadataEventTypeFIR, Feedback: &fb}, nil
+	case *proto.Event_RembEvent:
+		fb := feedbackEventFromProto(p.RembEvent)
+
+		return metadataEvent{Type: metadataEventTypeREMB, Feedback: &fb}, nil
+	case *proto.Event_NackEvent:
+		fb := feedbackEventFromProto(p.NackEvent)
+
+		return metadataEvent{Type: metadataEventTypeNACK, Feedback: &fb}, nil
+	case *proto.Event_SenderReportEvent:
+		fb := feedbackEventFromProto(p.SenderReportEvent)
+
+		return metadataEvent{Type: metadataEventTypeSenderReport, Feedback: &fb}, nil
+	case *proto.Event_PingEvent:
+		return metadataEvent{Type: metadataEventTypePing, Ping: &pingEventJSON{Nonce: p.PingEvent.Nonce}}, nil
+	case *proto.Event_PongEvent:
+		return metadataEvent{Type: metadataEventTypePong, Ping: &pingEventJSON{Nonce: p.PongEvent.Nonce}}, nil
+	default:
+		return metadataEvent{}, errors.Errorf("eventFromProto: unknown payload type: %T", pbEvent.Payload)
+	}
+}
+
+func newFeedbackEventProto(fb feedbackEventJSON) *proto.FeedbackEvent {
+	pb := &proto.FeedbackEvent{
+		TrackId:  string(fb.TrackID),
+		Ssrc:     fb.SSRC,
+		Bitrate:  fb.Bitrate,
+		FirSeqno: uint32(fb.FIRSeqNo),
+	}
+
+	for _, n := range fb.NACKs {
+		pb.Nacks = append(pb.Nacks, &proto.NACKPair{PacketId: uint32(n.PacketID), LostMask: uint32(n.LostMask)})
+	}
+
+	if fb.SenderReport != nil {
+		pb.SenderReport = &proto.SenderReport{
+			NtpTime:     fb.SenderReport.NTPTime,
+			RtpTime:     fb.SenderReport.RTPTime,
+			PacketCount: fb.SenderReport.PacketCount,
+			OctetCount:  fb.SenderReport.OctetCount,
+		}
+	}
+
+	return pb
+}
+
+func feedbackEventFromProto(pb *proto.FeedbackEvent) feedbackEventJSON {
+	fb := feedbackEventJSON{
+		TrackID:  transport.TrackID(pb.TrackId),
+		SSRC:     pb.Ssrc,
+		Bitrate:  pb.Bitrate,
+		FIRSeqNo: uint8(pb.FirSeqno),
+	}
+
+	for _, n := range pb.Nacks {
+		fb.NACKs = append(fb.NACKs, nackPairJSON{PacketID: uint16(n.PacketId), LostMask: uint16(n.LostMask)})
+	}
+
+	if pb.SenderReport != nil {
+		fb.SenderReport = &senderReportJSON{
+			NTPTime:     pb.SenderReport.NtpTime,
+			RTPTime:     pb.SenderReport.RtpTime,
+			PacketCount: pb.SenderReport.PacketCount,
+			OctetCount:  pb.SenderReport.OctetCount,
+		}
+	}
+
+	return fb
+}
+
+func newTrackEventProto(t trackEventJSON) *proto.TrackEvent {
+	return &proto.TrackEvent{
+		ClientId:  t.ClientID,
+		Type:      proto.TrackEventType(t.Type),
+		TrackInfo: newTrackInfoProto(t.TrackInfo),
+	}
+}
+
+func trackEventFromProto(pt *proto.TrackEvent) trackEventJSON {
+	return trackEventJSON{
+		ClientID:  pt.ClientId,
+		Type:      transport.TrackEventType(pt.Type),
+		TrackInfo: trackInfoFromProto(pt.TrackInfo),
+	}
+}
+
+func newTrackInfoProto(ti trackInfoJSON) *proto.TrackInfo {
+	pt := &proto.TrackInfo{
+		Id:           string(ti.Track.UniqueID()),
+		Kind:         trackKindToProto(ti.Codec.MimeType),
+		Mid:          ti.Mid,
+		PayloadType:  uint32(ti.Codec.PayloadType),
+		ClockRate:    ti.Codec.ClockRate,
+		MimeType:     ti.Codec.MimeType,
+		Channels:     ti.Codec.Channels,
+		SdpFmtpLine:  ti.Codec.SDPFmtpLine,
+		RtcpFeedback: ti.Codec.RTCPFeedback,
+	}
+
+	if fields, ok := transport.Track(ti.Track).(trackFields); ok {
+		pt.StreamId = fields.StreamID()
+		pt.Rid = fields.RID()
+		pt.Ssrc = fields.SSRC()
+	}
+
+	return pt
+}
+
+func trackInfoFromProto(pt *proto.TrackInfo) trackInfoJSON {
+	return trackInfoJSON{
+		Mid: pt.Mid,
+		Track: transport.SimpleTrack{
+			TrackID:     transport.TrackID(pt.Id),
+			StreamID:    pt.StreamId,
+			RID:         pt.Rid,
+			SSRC:        pt.Ssrc,
+			PayloadType: uint8(pt.PayloadType),
+		},
+		Codec: transport.Codec{
+			MimeType:     pt.MimeType,
+			ClockRate:    pt.ClockRate,
+			Channels:     pt.Channels,
+			PayloadType:  uint8(pt.PayloadType),
+			SDPFmtpLine:  pt.SdpFmtpLine,
+			RTCPFeedback: pt.RtcpFeedback,
+		},
+	}
+}
+
+// trackKindToProto classifies a track from its negotiated codec MIME type.
+// Data tracks have no codec and map to TRACK_KIND_DATA.
+func trackKindToProto(mimeType string) proto.TrackKind {
+	switch {
+	case strings.HasPrefix(strings.ToLower(mimeType), "audio/"):
+		return proto.TrackKind_TRACK_KIND_AUDIO
+	case strings.HasPrefix(strings.ToLower(mimeType), "video/"):
+		return proto.TrackKind_TRACK_KIND_VIDEO
+	case mimeType == "":
+		return proto.TrackKind_TRACK_KIND_DATA
+	default:
+		return proto.TrackKind_TRACK_KIND_UNKNOWN
+	}
+}
@@ -0,0 +1,109 @@
+package servertransport
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// dribbleReader returns at most chunkSize bytes per Read, so a Decode that
+// relies on a single Read returning a whole header or payload would break
+// against it. lengthPrefixedCodec is expected to survive it because it reads
+// through io.ReadFull.
+type dribbleReader struct {
+	r         io.Reader
+	chunkSize int
+}
+
+func (d *dribbleReader) Read(p []byte) (int, error) {
+	if len(p) > d.chunkSize {
+		p = p[:d.chunkSize]
+	}
+
+	return d.r.Read(p)
+}
+
+func TestLengthPrefixedCodec_DecodeSplitReads(t *testing.T) {
+	event := metadataEvent{
+		Type: metadataEventTypeInit,
+		Init: &initEventJSON{ClientID: "client-1", ProtocolVersion: 1},
+	}
+
+	var buf bytes.Buffer
+
+	codec := NewLengthPrefixedCodec(0)
+
+	if err := codec.Encode(&buf, event); err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	got, err := codec.Decode(&dribbleReader{r: &buf, chunkSize: 3})
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+
+	if !reflect.DeepEqual(event, got) {
+		t.Fatalf("decode mismatch:\n  want: %+v\n  got:  %+v", event, got)
+	}
+}
+
+func TestLengthPrefixedCodec_DecodeOversizeFrameRejected(t *testing.T) {
+	event := metadataEvent{
+		Type: metadataEventTypeInit,
+		Init: &initEventJSON{ClientID: "client-1", ProtocolVersion: 1},
+	}
+
+	var buf bytes.Buffer
+
+	if err := NewLengthPrefixedCodec(0).Encode(&buf, event); err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	codec := NewLengthPrefixedCodec(4)
+
+	if _, err := codec.Decode(&buf); err == nil {
+		t.Fatal("Decode: expected an error for a frame larger than maxFrameSize, got nil")
+	}
+}
+
+func TestLengthPrefixedCodec_DecodeConsumesExactlyOneFrame(t *testing.T) {
+	first := metadataEvent{
+		Type: metadataEventTypeInit,
+		Init: &initEventJSON{ClientID: "client-1", ProtocolVersion: 1},
+	}
+	second := metadataEvent{
+		Type: metadataEventTypeInit,
+		Init: &initEventJSON{ClientID: "client-2", ProtocolVersion: 1},
+	}
+
+	var buf bytes.Buffer
+
+	codec := NewLengthPrefixedCodec(0)
+
+	if err := codec.Encode(&buf, first); err != nil {
+		t.Fatalf("Encode(first): %s", err)
+	}
+
+	if err := codec.Encode(&buf, second); err != nil {
+		t.Fatalf("Encode(second): %s", err)
+	}
+
+	got1, err := codec.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode(first): %s", err)
+	}
+
+	if !reflect.DeepEqual(first, got1) {
+		t.Fatalf("first decode mismatch:\n  want: %+v\n  got:  %+v", first, got1)
+	}
+
+	got2, err := codec.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode(second): %s", err)
+	}
+
+	if !reflect.DeepEqual(second, got2) {
+		t.Fatalf("second decode mismatch:\n  want: %+v\n  got:  %+v", second, got2)
+	}
+}
@@ -10,8 +10,22 @@ type metadataEvent struct {
 	// Type must always be set
 	Type metadataEventType `json:"type"`
 
-	// Track will be set only when Type is metadataEventTypeTrackEvent.
+	// Track will be set only when Type is metadataEventTypeTrack.
 	Track *trackEventJSON `json:"trackEvent"`
+
+	// Init will be set only when Type is metadataEventTypeInit.
+	Init *initEventJSON `json:"init"`
+
+	// Bye will be set only when Type is metadataEventTypeBye.
+	Bye *byeEventJSON `json:"bye"`
+
+	// Feedback will be set only when Type is one of the feedback event
+	// types (metadataEventTypePLI, ..., metadataEventTypeSenderReport).
+	Feedback *feedbackEventJSON `json:"feedback"`
+
+	// Ping will be set only when Type is metadataEventTypePing or
+	// metadataEventTypePong.
+	Ping *pingEventJSON `json:"ping"`
 }
 
 type metadataEventType int
@@ -19,23 +33,76 @@ type metadataEventType int
 const (
 	// Track event contains the information about tracks.
 	metadataEventTypeTrack metadataEventType = iota + 1
+	// Init event is sent once, as soon as a MetadataTransport is created, to
+	// announce this side's protocol version. A receiver whose version differs
+	// logs the mismatch and carries on; no version is negotiated, and the
+	// Init event is not ordered before other events sent right after creation.
+	metadataEventTypeInit
+	// Bye event is sent right before a MetadataTransport closes its conn on
+	// purpose, so the remote side can tell a clean shutdown from a dropped
+	// connection.
+	metadataEventTypeBye
+	// PLI requests a keyframe for a track, forwarded upstream from whichever
+	// downstream peer lost a frame.
+	metadataEventTypePLI
+	// FIR is a stronger keyframe request than PLI, for peers that don't
+	// support PLI.
+	metadataEventTypeFIR
+	// REMB carries a receiver-estimated maximum bitrate for a track.
+	metadataEventTypeREMB
+	// NACK carries sequence numbers of RTP packets that need retransmitting.
+	metadataEventTypeNACK
+	// SenderReport forwards RTCP sender report timing for a track.
+	metadataEventTypeSenderReport
+	// Ping is sent periodically by PeerManager to check a peer is still
+	// alive. The receiving MetadataTransport answers with a Pong carrying
+	// the same nonce.
+	metadataEventTypePing
+	// Pong answers a Ping, echoing its nonce back.
+	metadataEventTypePong
 )
 
 func (m metadataEventType) String() string {
 	switch m {
 	case metadataEventTypeTrack:
 		return "TrackEvent"
+	case metadataEventTypeInit:
+		return "InitEvent"
+	case metadataEventTypeBye:
+		return "ByeEvent"
+	case metadataEventTypePLI:
+		return "PLI"
+	case metadataEventTypeFIR:
+		return "FIR"
+	case metadataEventTypeREMB:
+		return "REMB"
+	case metadataEventTypeNACK:
+		return "NACK"
+	case metadataEventTypeSenderReport:
+		return "SenderReport"
+	case metadataEventTypePing:
+		return "Ping"
+	case metadataEventTypePong:
+		return "Pong"
 	default:
 		return fmt.Sprintf("Unknown(%d)", m)
 	}
 }
 
+// metadataProtocolVersion is sent in every initEventJSON/InitEvent. Bump it
+// whenever a wire-incompatible change is made to the metadata protocol.
+const metadataProtocolVersion = 1
+
 type initEventJSON struct {
-	ClientID string
+	ClientID        string
+	ProtocolVersion int
+	Capabilities    []string
 }
 
 // trackEventJSON is used instead of TrackEvent because JSON cannot deserialize
-// to Track interface, so a SimpleTrack is used.
+// to Track interface, so a SimpleTrack is used. toSimpleTrack builds that
+// SimpleTrack from any transport.Track implementation, reading through the
+// trackFields interface rather than asserting a concrete type.
 type trackEventJSON struct {
 	ClientID  string
 	TrackInfo trackInfoJSON
@@ -43,19 +110,42 @@ type trackEventJSON struct {
 }
 
 func newTrackEventJSON(trackEvent transport.TrackEvent) trackEventJSON {
-	// TODO watch out for possible panics.
-	track := trackEvent.TrackWithMID.Track.(transport.SimpleTrack)
+	track := toSimpleTrack(trackEvent.TrackWithMID.Track)
 
 	return trackEventJSON{
 		ClientID: trackEvent.ClientID,
 		TrackInfo: trackInfoJSON{
 			Track: track,
 			Mid:   trackEvent.TrackWithMID.Mid,
+			Codec: trackEvent.TrackWithMID.Codec,
 		},
 		Type: trackEvent.Type,
 	}
 }
 
+// toSimpleTrack converts any transport.Track implementation to a
+// transport.SimpleTrack so it can round-trip through JSON, which cannot
+// deserialize to an interface. Fields beyond UniqueID are only populated
+// when the concrete type implements trackFields; this is what lets a
+// *ServerTrack (which embeds SimpleTrack) be sent back out without a panic,
+// e.g. when relaying a remote track to a third server.
+func toSimpleTrack(track transport.Track) transport.SimpleTrack {
+	if simple, ok := track.(transport.SimpleTrack); ok {
+		return simple
+	}
+
+	simple := transport.SimpleTrack{TrackID: track.UniqueID()}
+
+	if fields, ok := track.(trackFields); ok {
+		simple.StreamID = fields.StreamID()
+		simple.RID = fields.RID()
+		simple.SSRC = fields.SSRC()
+		simple.PayloadType = fields.PayloadType()
+	}
+
+	return simple
+}
+
 // trackEvent converts the trackEventJSON to TrackEvent.
 func (t trackEventJSON) trackEvent(clientID string) transport.TrackEvent {
 	return transport.TrackEvent{
@@ -63,6 +153,7 @@ func (t trackEventJSON) trackEvent(clientID string) transport.TrackEvent {
 		TrackWithMID: transport.TrackInfo{
 			Track: t.TrackInfo.Track,
 			Mid:   t.TrackInfo.Mid,
+			Codec: t.TrackInfo.Codec,
 		},
 		Type: t.Type,
 	}
@@ -71,6 +162,138 @@ func (t trackEventJSON) trackEvent(clientID string) transport.TrackEvent {
 type trackInfoJSON struct {
 	Track transport.SimpleTrack
 	Mid   string
+	Codec transport.Codec
 }
 
 type byeEventJSON struct{}
+
+// pingEventJSON carries a monotonic nonce, used for both Ping and Pong: a
+// Pong echoes back the nonce of the Ping it answers.
+type pingEventJSON struct {
+	Nonce uint64
+}
+
+// feedbackEventJSON carries an RTCP-style feedback message for the track
+// identified by TrackID. Only the fields relevant to the enclosing
+// metadataEvent's Type are meaningful; see transport.Feedback.
+type feedbackEventJSON struct {
+	TrackID transport.TrackID
+	SSRC    uint32
+
+	// Bitrate is set only for metadataEventTypeREMB, in bits per second.
+	Bitrate uint64
+
+	// FIRSeqNo is set only for metadataEventTypeFIR.
+	FIRSeqNo uint8
+
+	// NACKs is set only for metadataEventTypeNACK. Each pair packs a base
+	// sequence number with a bitmask (blp, as in RFC 4585 section 6.2.1) of
+	// up to 16 additional lost packets following it.
+	NACKs []nackPairJSON
+
+	// SenderReport is set only for metadataEventTypeSenderReport.
+	SenderReport *senderReportJSON
+}
+
+type nackPairJSON struct {
+	PacketID uint16
+	LostMask uint16
+}
+
+type senderReportJSON struct {
+	NTPTime     uint64
+	RTPTime     uint32
+	PacketCount uint32
+	OctetCount  uint32
+}
+
+// feedbackMetadataEventType returns the metadataEventType that carries fb on
+// the wire.
+func feedbackMetadataEventType(fb transport.Feedback) metadataEventType {
+	switch fb.Type {
+	case transport.FeedbackTypePLI:
+		return metadataEventTypePLI
+	case transport.FeedbackTypeFIR:
+		return metadataEventTypeFIR
+	case transport.FeedbackTypeREMB:
+		return metadataEventTypeREMB
+	case transport.FeedbackTypeNACK:
+		return metadataEventTypeNACK
+	case transport.FeedbackTypeSenderReport:
+		return metadataEventTypeSenderReport
+	default:
+		return 0
+	}
+}
+
+// feedbackTypeFromMetadataEvent is the inverse of feedbackMetadataEventType.
+func feedbackTypeFromMetadataEvent(t metadataEventType) transport.FeedbackType {
+	switch t {
+	case metadataEventTypePLI:
+		return transport.FeedbackTypePLI
+	case metadataEventTypeFIR:
+		return transport.FeedbackTypeFIR
+	case metadataEventTypeREMB:
+		return transport.FeedbackTypeREMB
+	case metadataEventTypeNACK:
+		return transport.FeedbackTypeNACK
+	case metadataEventTypeSenderReport:
+		return transport.FeedbackTypeSenderReport
+	default:
+		return 0
+	}
+}
+
+func newFeedbackEventJSON(trackID transport.TrackID, fb transport.Feedback) feedbackEventJSON {
+	event := feedbackEventJSON{
+		TrackID:  trackID,
+		SSRC:     fb.SSRC,
+		Bitrate:  fb.Bitrate,
+		FIRSeqNo: fb.FIRSeqNo,
+	}
+
+	for _, n := range fb.NACKs {
+		event.NACKs = append(event.NACKs, nackPairJSON{PacketID: n.PacketID, LostMask: n.LostMask})
+	}
+
+	if fb.SenderReport != nil {
+		event.SenderReport = &senderReportJSON{
+			NTPTime:     fb.SenderReport.NTPTime,
+			RTPTime:     fb.SenderReport.RTPTime,
+			PacketCount: fb.SenderReport.PacketCount,
+			OctetCount:  fb.SenderReport.OctetCount,
+		}
+	}
+
+	return event
+}
+
+// feedbackEvent converts a feedbackEventJSON read off the wire, together
+// with the metadataEventType it was carried in, to a transport.FeedbackEvent.
+func (f feedbackEventJSON) feedbackEvent(clientID string, eventType metadataEventType) transport.FeedbackEvent {
+	fb := transport.Feedback{
+		Type:     feedbackTypeFromMetadataEvent(eventType),
+		SSRC:     f.SSRC,
+		Bitrate:  f.Bitrate,
+		FIRSeqNo: f.FIRSeqNo,
+	}
+
+	for _, n := range f.NACKs {
+		fb.NACKs = append(fb.NACKs, transport.NACKPair{PacketID: n.PacketID, LostMask: n.LostMask})
+	}
+
+	if f.SenderReport != nil {
+		fb.SenderReport = &transport.SenderReport{
+			NTPTime:     f.SenderReport.NTPTime,
+			RTPTime:     f.SenderReport.RTPTime,
+			PacketCount: f.SenderReport.PacketCount,
+			OctetCount:  f.SenderReport.OctetCount,
+		}
+	}
+
+	return transport.FeedbackEvent{
+		ClientID: clientID,
+		TrackID:  f.TrackID,
+		Feedback: fb,
+	}
+}
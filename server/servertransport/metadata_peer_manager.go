@@ -0,0 +1,589 @@
+package servertransport
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/peer-calls/peer-calls/server/logger"
+	"github.com/peer-calls/peer-calls/server/transport"
+)
+
+// ConnDialer opens a fresh conn to a peer at addr. PeerManager calls it once
+// per connection attempt, including every reconnect.
+type ConnDialer interface {
+	Dial(addr string) (io.ReadWriteCloser, error)
+}
+
+// PeerState describes the lifecycle of a single PeerManager-managed peer.
+type PeerState int
+
+const (
+	PeerStateConnecting PeerState = iota + 1
+	PeerStateActive
+	PeerStateReconnecting
+	PeerStateClosed
+)
+
+func (s PeerState) String() string {
+	switch s {
+	case PeerStateConnecting:
+		return "Connecting"
+	case PeerStateActive:
+		return "Active"
+	case PeerStateReconnecting:
+		return "Reconnecting"
+	case PeerStateClosed:
+		return "Closed"
+	default:
+		return fmt.Sprintf("Unknown(%d)", s)
+	}
+}
+
+// PeerStateChange is delivered on PeerManager.StateChanges whenever a peer's
+// state transitions.
+type PeerStateChange struct {
+	PeerID string
+	State  PeerState
+}
+
+// PeerManagerParams configures a PeerManager.
+type PeerManagerParams struct {
+	Log    logger.Logger
+	Dialer ConnDialer
+
+	// Codec frames the MetadataTransport built on top of every conn Dialer
+	// returns. Defaults to NewLengthPrefixedCodec(0), since a ConnDialer talks
+	// to another Peer-Calls server over the network, i.e. the stream-oriented
+	// case MetadataTransport's default datagram codec silently corrupts.
+	Codec MetadataCodec
+
+	// Tracer observes every MetadataTransport PeerManager creates. Defaults to
+	// a no-op tracer.
+	Tracer MetadataTracer
+
+	// PingInterval is how often a Ping is sent to every peer with an active
+	// conn. Defaults to 5s.
+	PingInterval time.Duration
+
+	// MaxMissedPongs is how many consecutive Pings can go unanswered before a
+	// peer's conn is declared dead and reconnected. Defaults to 3.
+	MaxMissedPongs int
+
+	// MinBackoff and MaxBackoff bound the exponential backoff used between
+	// reconnect attempts. Default to 1s and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// MinConnUptime is how long a conn must stay Active before a disconnect
+	// resets the backoff back to MinBackoff. A conn that dies sooner than this
+	// grows the backoff instead, so a peer that accepts and immediately drops
+	// the conn (crash-looping remote, auth rejection, ...) backs off like a
+	// Dial failure would, rather than being retried at MinBackoff forever.
+	// Defaults to MaxBackoff.
+	MinConnUptime time.Duration
+}
+
+func (p *PeerManagerParams) setDefaults() {
+	if p.Codec == nil {
+		p.Codec = NewLengthPrefixedCodec(0)
+	}
+
+	if p.Tracer == nil {
+		p.Tracer = noopTracer{}
+	}
+
+	if p.PingInterval <= 0 {
+		p.PingInterval = 5 * time.Second
+	}
+
+	if p.MaxMissedPongs <= 0 {
+		p.MaxMissedPongs = 3
+	}
+
+	if p.MinBackoff <= 0 {
+		p.MinBackoff = time.Second
+	}
+
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+
+	if p.MinConnUptime <= 0 {
+		p.MinConnUptime = p.MaxBackoff
+	}
+}
+
+// PeerManager owns reconnecting MetadataTransports for a set of peers,
+// modeled after the connection-management half of etcd's rafthttp.Transport.
+// A MetadataTransport itself stays oblivious to reconnection: PeerManager
+// just recreates one on top of each new conn and replays every track it
+// currently holds for the peer, so the remote side resyncs.
+type PeerManager struct {
+	params PeerManagerParams
+	log    logger.Logger
+
+	mu    sync.RWMutex
+	peers map[string]*managedPeer
+
+	stateChangeCh chan PeerStateChange
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+// NewPeerManager creates a PeerManager. Call AddPeer to start managing a
+// peer's conn.
+func NewPeerManager(params PeerManagerParams) *PeerManager {
+	params.setDefaults()
+
+	return &PeerManager{
+		params:        params,
+		log:           params.Log.WithNamespaceAppended("peer_manager"),
+		peers:         map[string]*managedPeer{},
+		stateChangeCh: make(chan PeerStateChange, stateChangeBuffer),
+		closeCh:       make(chan struct{}),
+	}
+}
+
+// stateChangeBuffer bounds stateChangeCh. State() and StateChanges() are
+// documented as two independent ways to observe a peer, so a caller that
+// only ever calls State() must not make delivering a state change block.
+const stateChangeBuffer = 16
+
+// StateChanges delivers a PeerStateChange every time a managed peer
+// transitions state. Delivery is best-effort: if the channel is full
+// because nobody is draining it, the change is dropped and State(peerID)
+// remains the source of truth.
+func (m *PeerManager) StateChanges() <-chan PeerStateChange {
+	return m.stateChangeCh
+}
+
+// State returns the current state of peerID, or PeerStateClosed if peerID is
+// not managed.
+func (m *PeerManager) State(peerID string) PeerState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	peer, ok := m.peers[peerID]
+	if !ok {
+		return PeerStateClosed
+	}
+
+	return peer.state()
+}
+
+// AddPeer starts connecting to peerID at addr, reconnecting with backoff for
+// as long as the peer stays managed. Calling AddPeer again for an already
+// managed peerID is a no-op; use UpdatePeer to change its address.
+func (m *PeerManager) AddPeer(peerID string, addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.peers[peerID]; ok {
+		return
+	}
+
+	peer := newManagedPeer(peerID, addr)
+	m.peers[peerID] = peer
+
+	go m.run(peer)
+}
+
+// UpdatePeer changes the address used to reach peerID. The current conn, if
+// any, is torn down so the next reconnect picks up the new address.
+func (m *PeerManager) UpdatePeer(peerID string, addr string) {
+	m.mu.RLock()
+	peer, ok := m.peers[peerID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	peer.setAddr(addr)
+	peer.closeConn()
+}
+
+// RemovePeer stops managing peerID and closes its conn.
+func (m *PeerManager) RemovePeer(peerID string) {
+	m.mu.Lock()
+	peer, ok := m.peers[peerID]
+	delete(m.peers, peerID)
+	m.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	m.setState(peer, PeerStateClosed)
+	peer.stop()
+}
+
+// Close stops managing every peer.
+func (m *PeerManager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.closeCh)
+	})
+
+	m.mu.Lock()
+	peers := m.peers
+	m.peers = map[string]*managedPeer{}
+	m.mu.Unlock()
+
+	for _, peer := range peers {
+		peer.stop()
+	}
+}
+
+// AddTrack adds track to every managed peer with an active conn, and
+// remembers it so it is replayed to peers that connect or reconnect
+// afterwards.
+func (m *PeerManager) AddTrack(track transport.Track) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, peer := range m.peers {
+		peer.addTrack(track)
+	}
+}
+
+// RemoveTrack removes trackID from every managed peer.
+func (m *PeerManager) RemoveTrack(trackID transport.TrackID) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, peer := range m.peers {
+		peer.removeTrack(trackID)
+	}
+}
+
+// setState updates peer's state and reports the change on stateChangeCh,
+// unless the state did not actually change. The send is non-blocking:
+// State(peerID) is always accurate regardless of whether anything is
+// draining stateChangeCh, so delivering a change must never gate the
+// reconnect loop.
+func (m *PeerManager) setState(peer *managedPeer, state PeerState) {
+	if !peer.setState(state) {
+		return
+	}
+
+	change := PeerStateChange{PeerID: peer.peerID, State: state}
+
+	select {
+	case m.stateChangeCh <- change:
+	default:
+		m.log.Info("setState: stateChangeCh full, dropping change", logger.Ctx{
+			"peer_id": peer.peerID,
+			"state":   state.String(),
+		})
+	}
+}
+
+// run dials peer, brings up a MetadataTransport on top of the conn, and
+// keeps reconnecting with exponential backoff until peer is removed or the
+// manager is closed.
+func (m *PeerManager) run(peer *managedPeer) {
+	peer.resetBackoff(m.params.MinBackoff)
+
+	for {
+		m.setState(peer, PeerStateConnecting)
+
+		conn, err := m.params.Dialer.Dial(peer.addr())
+		if err != nil {
+			m.log.Error("Dial", errors.Trace(err), logger.Ctx{"peer_id": peer.peerID})
+
+			if !m.sleep(peer, peer.backoff()) {
+				return
+			}
+
+			peer.growBackoff(m.params.MaxBackoff)
+
+			continue
+		}
+
+		if !m.runConn(peer, conn) {
+			return
+		}
+	}
+}
+
+// runConn wraps conn in a MetadataTransport, replays the tracks known for
+// peer, and blocks exchanging Pings/Pongs until the conn is declared dead,
+// forced closed by UpdatePeer, or peer/the manager is closed. It reports
+// whether run should keep retrying peer.
+func (m *PeerManager) runConn(peer *managedPeer, conn io.ReadWriteCloser) bool {
+	mt := NewMetadataTransport(
+		m.params.Log, conn, peer.peerID, WithCodec(m.params.Codec), WithTracer(m.params.Tracer),
+	)
+	defer mt.Close()
+
+	peer.attach(mt)
+	defer peer.detach()
+
+	for _, track := range peer.tracks() {
+		if err := mt.AddTrack(track); err != nil {
+			m.log.Error("AddTrack: replay", errors.Trace(err), logger.Ctx{"peer_id": peer.peerID})
+		}
+	}
+
+	m.setState(peer, PeerStateActive)
+
+	connectedAt := time.Now()
+
+	ticker := time.NewTicker(m.params.PingInterval)
+	defer ticker.Stop()
+
+	var nonce uint64
+
+	missedPongs := 0
+
+	// disconnect handles a dead conn detected inside this loop (as opposed to
+	// one forced by UpdatePeer/RemovePeer/Close): it grows the backoff unless
+	// the conn stayed up for at least MinConnUptime, then sleeps for it before
+	// the next reconnect attempt.
+	disconnect := func() bool {
+		if time.Since(connectedAt) >= m.params.MinConnUptime {
+			peer.resetBackoff(m.params.MinBackoff)
+		} else {
+			peer.growBackoff(m.params.MaxBackoff)
+		}
+
+		m.setState(peer, PeerStateReconnecting)
+
+		return m.sleep(peer, peer.backoff())
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			missedPongs++
+
+			if missedPongs > m.params.MaxMissedPongs {
+				return disconnect()
+			}
+
+			nonce++
+
+			if err := mt.SendPing(nonce); err != nil {
+				m.log.Error("SendPing", errors.Trace(err), logger.Ctx{"peer_id": peer.peerID})
+			}
+		case <-mt.PongChannel():
+			missedPongs = 0
+		case <-mt.Done():
+			return disconnect()
+		case <-peer.closeConnSignal():
+			m.setState(peer, PeerStateReconnecting)
+
+			return true
+		case <-peer.closeCh:
+			return false
+		case <-m.closeCh:
+			return false
+		}
+	}
+}
+
+// sleep waits for d, or until peer is stopped or the manager is closed. It
+// reports whether the caller should keep retrying.
+func (m *PeerManager) sleep(peer *managedPeer, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-peer.closeCh:
+		return false
+	case <-m.closeCh:
+		return false
+	}
+}
+
+func nextBackoff(cur time.Duration, max time.Duration) time.Duration {
+	next := cur * 2
+
+	if next > max {
+		next = max
+	}
+
+	return next
+}
+
+// managedPeer holds the reconnect state PeerManager tracks for a single
+// peer, independently of whichever MetadataTransport currently represents
+// its conn.
+type managedPeer struct {
+	peerID string
+
+	mu         sync.Mutex
+	addrVal    string
+	stateVal   PeerState
+	backoffVal time.Duration
+	transport  *MetadataTransport
+	tracksVal  map[transport.TrackID]transport.Track
+
+	// closeConnCh is closed to force the current conn to be torn down, e.g.
+	// when UpdatePeer changes the address. It is replaced with a fresh
+	// channel every time it fires.
+	closeConnCh chan struct{}
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+func newManagedPeer(peerID string, addr string) *managedPeer {
+	return &managedPeer{
+		peerID:      peerID,
+		addrVal:     addr,
+		stateVal:    PeerStateConnecting,
+		tracksVal:   map[transport.TrackID]transport.Track{},
+		closeConnCh: make(chan struct{}),
+		closeCh:     make(chan struct{}),
+	}
+}
+
+func (p *managedPeer) addr() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.addrVal
+}
+
+func (p *managedPeer) setAddr(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.addrVal = addr
+}
+
+func (p *managedPeer) state() PeerState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stateVal
+}
+
+// setState updates the state and reports whether it actually changed.
+func (p *managedPeer) setState(state PeerState) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stateVal == state {
+		return false
+	}
+
+	p.stateVal = state
+
+	return true
+}
+
+// backoff returns the duration to wait before the next reconnect attempt.
+func (p *managedPeer) backoff() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.backoffVal
+}
+
+// growBackoff doubles the backoff, capped at max.
+func (p *managedPeer) growBackoff(max time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.backoffVal = nextBackoff(p.backoffVal, max)
+}
+
+// resetBackoff sets the backoff back down to min, e.g. once a conn has
+// proven itself stable.
+func (p *managedPeer) resetBackoff(min time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.backoffVal = min
+}
+
+func (p *managedPeer) attach(mt *MetadataTransport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.transport = mt
+}
+
+func (p *managedPeer) detach() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.transport = nil
+}
+
+// addTrack remembers track for replay on future (re)connects, and pushes it
+// to the live conn right away if there is one.
+func (p *managedPeer) addTrack(track transport.Track) {
+	p.mu.Lock()
+	p.tracksVal[track.UniqueID()] = track
+	mt := p.transport
+	p.mu.Unlock()
+
+	if mt == nil {
+		return
+	}
+
+	if err := mt.AddTrack(track); err != nil {
+		// Best-effort: if the conn is on its way down, the replay at the top
+		// of the next runConn will pick this track up once it reconnects.
+		_ = err
+	}
+}
+
+func (p *managedPeer) removeTrack(trackID transport.TrackID) {
+	p.mu.Lock()
+	delete(p.tracksVal, trackID)
+	mt := p.transport
+	p.mu.Unlock()
+
+	if mt != nil {
+		_ = mt.RemoveTrack(trackID)
+	}
+}
+
+func (p *managedPeer) tracks() []transport.Track {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tracks := make([]transport.Track, 0, len(p.tracksVal))
+
+	for _, track := range p.tracksVal {
+		tracks = append(tracks, track)
+	}
+
+	return tracks
+}
+
+// closeConn forces the current conn to be torn down, so run reconnects and
+// picks up any address change made via setAddr.
+func (p *managedPeer) closeConn() {
+	p.mu.Lock()
+	ch := p.closeConnCh
+	p.closeConnCh = make(chan struct{})
+	p.mu.Unlock()
+
+	close(ch)
+}
+
+func (p *managedPeer) closeConnSignal() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.closeConnCh
+}
+
+// stop marks peer as removed. The conn, if any, is torn down by runConn's
+// own deferred mt.Close() once it observes closeCh.
+func (p *managedPeer) stop() {
+	p.closeOnce.Do(func() {
+		close(p.closeCh)
+	})
+}
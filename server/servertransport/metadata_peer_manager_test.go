@@ -0,0 +1,144 @@
+package servertransport
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/peer-calls/peer-calls/server/logger"
+)
+
+func TestManagedPeerBackoff(t *testing.T) {
+	peer := newManagedPeer("peer-1", "addr-1")
+
+	peer.resetBackoff(time.Second)
+
+	if got := peer.backoff(); got != time.Second {
+		t.Fatalf("backoff after reset: got %s, want %s", got, time.Second)
+	}
+
+	peer.growBackoff(10 * time.Second)
+
+	if got := peer.backoff(); got != 2*time.Second {
+		t.Fatalf("backoff after one grow: got %s, want %s", got, 2*time.Second)
+	}
+
+	peer.growBackoff(10 * time.Second)
+	peer.growBackoff(10 * time.Second)
+	peer.growBackoff(10 * time.Second)
+
+	if got := peer.backoff(); got != 10*time.Second {
+		t.Fatalf("backoff should be capped at max: got %s, want %s", got, 10*time.Second)
+	}
+
+	peer.resetBackoff(time.Second)
+
+	if got := peer.backoff(); got != time.Second {
+		t.Fatalf("backoff after second reset: got %s, want %s", got, time.Second)
+	}
+}
+
+// blackholeConn accepts every Write without error, and blocks every Read
+// until Close is called, simulating a remote that accepts the conn but never
+// answers anything on it. It drives PeerManager's missed-pongs path without
+// needing a real network peer.
+type blackholeConn struct {
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newBlackholeConn() *blackholeConn {
+	return &blackholeConn{closed: make(chan struct{})}
+}
+
+func (c *blackholeConn) Read(p []byte) (int, error) {
+	<-c.closed
+
+	return 0, io.EOF
+}
+
+func (c *blackholeConn) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (c *blackholeConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	return nil
+}
+
+// countingDialer hands out a fresh blackholeConn on every Dial, and counts
+// how many times it was called.
+type countingDialer struct {
+	mu    sync.Mutex
+	dials int
+}
+
+func (d *countingDialer) Dial(addr string) (io.ReadWriteCloser, error) {
+	d.mu.Lock()
+	d.dials++
+	d.mu.Unlock()
+
+	return newBlackholeConn(), nil
+}
+
+func (d *countingDialer) dialCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dials
+}
+
+// TestPeerManagerReconnectsOnMissedPongs drives a peer whose conn never
+// answers a Ping, and checks that PeerManager declares it dead via the
+// missed-pongs path (Active -> Reconnecting) and then dials it again. It does
+// not check how long PeerManager backs off between attempts.
+func TestPeerManagerReconnectsOnMissedPongs(t *testing.T) {
+	dialer := &countingDialer{}
+
+	pm := NewPeerManager(PeerManagerParams{
+		Log:            logger.Logger{},
+		Dialer:         dialer,
+		PingInterval:   10 * time.Millisecond,
+		MaxMissedPongs: 1,
+		MinBackoff:     20 * time.Millisecond,
+		MaxBackoff:     40 * time.Millisecond,
+	})
+	defer pm.Close()
+
+	pm.AddPeer("peer-1", "addr-1")
+
+	deadline := time.After(2 * time.Second)
+
+	seenActive := false
+	seenReconnecting := false
+
+	for !seenActive || !seenReconnecting {
+		select {
+		case change := <-pm.StateChanges():
+			if change.PeerID != "peer-1" {
+				continue
+			}
+
+			switch change.State {
+			case PeerStateActive:
+				seenActive = true
+			case PeerStateReconnecting:
+				if seenActive {
+					seenReconnecting = true
+				}
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for Active -> Reconnecting, seenActive=%v seenReconnecting=%v", seenActive, seenReconnecting)
+		}
+	}
+
+	// Give the reconnect a chance to run after the backoff sleep, so the
+	// dialer has been called again.
+	time.Sleep(200 * time.Millisecond)
+
+	if got := dialer.dialCount(); got < 2 {
+		t.Fatalf("expected at least 2 dial attempts, got %d", got)
+	}
+}
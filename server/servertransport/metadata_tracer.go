@@ -0,0 +1,40 @@
+package servertransport
+
+// MetadataTracer receives hooks from MetadataTransport so operators can see
+// server-to-server track churn that is otherwise invisible. All methods are
+// called synchronously, from the transport's read/write loops or from the
+// goroutine calling into the transport (NewMetadataTransport, Close, sends),
+// so implementations must not block.
+type MetadataTracer interface {
+	// EventSent is called once an event has been written to the conn.
+	EventSent(clientID string, ev metadataEvent)
+	// EventReceived is called once an event has been read off the conn.
+	EventReceived(clientID string, ev metadataEvent)
+	// EventDropped is called when an event is discarded instead of being
+	// delivered, e.g. a duplicate track-add refresh or a send that lost the
+	// race with Close.
+	EventDropped(clientID string, ev metadataEvent, reason string)
+	// ReadError is called when reading from the conn fails. The read loop
+	// stops right after.
+	ReadError(clientID string, err error)
+	// WriteError is called when writing to the conn fails. The write loop
+	// keeps running, since a single bad write does not mean the conn is dead.
+	WriteError(clientID string, err error)
+	// PeerAttached is called once, when a MetadataTransport is created for a
+	// peer.
+	PeerAttached(clientID string)
+	// PeerDetached is called once, when a MetadataTransport's Close has run
+	// to completion.
+	PeerDetached(clientID string)
+}
+
+// noopTracer is the MetadataTracer used when none is configured.
+type noopTracer struct{}
+
+func (noopTracer) EventSent(string, metadataEvent)            {}
+func (noopTracer) EventReceived(string, metadataEvent)        {}
+func (noopTracer) EventDropped(string, metadataEvent, string) {}
+func (noopTracer) ReadError(string, error)                    {}
+func (noopTracer) WriteError(string, error)                   {}
+func (noopTracer) PeerAttached(string)                        {}
+func (noopTracer) PeerDetached(string)                        {}
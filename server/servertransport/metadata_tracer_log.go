@@ -0,0 +1,60 @@
+package servertransport
+
+import "github.com/peer-calls/peer-calls/server/logger"
+
+// logTracer is a MetadataTracer that reports events through the repo's
+// structured logger.
+type logTracer struct {
+	log logger.Logger
+}
+
+// NewLogTracer returns a MetadataTracer that logs events via log.
+func NewLogTracer(log logger.Logger) MetadataTracer {
+	return &logTracer{log: log.WithNamespaceAppended("metadata_tracer")}
+}
+
+func (t *logTracer) EventSent(clientID string, ev metadataEvent) {
+	t.log.Trace("EventSent", logger.Ctx{
+		"client_id":      clientID,
+		"metadata_event": ev.Type,
+	})
+}
+
+func (t *logTracer) EventReceived(clientID string, ev metadataEvent) {
+	t.log.Trace("EventReceived", logger.Ctx{
+		"client_id":      clientID,
+		"metadata_event": ev.Type,
+	})
+}
+
+func (t *logTracer) EventDropped(clientID string, ev metadataEvent, reason string) {
+	t.log.Info("EventDropped", logger.Ctx{
+		"client_id":      clientID,
+		"metadata_event": ev.Type,
+		"reason":         reason,
+	})
+}
+
+func (t *logTracer) ReadError(clientID string, err error) {
+	t.log.Error("ReadError", err, logger.Ctx{
+		"client_id": clientID,
+	})
+}
+
+func (t *logTracer) WriteError(clientID string, err error) {
+	t.log.Error("WriteError", err, logger.Ctx{
+		"client_id": clientID,
+	})
+}
+
+func (t *logTracer) PeerAttached(clientID string) {
+	t.log.Info("PeerAttached", logger.Ctx{
+		"client_id": clientID,
+	})
+}
+
+func (t *logTracer) PeerDetached(clientID string) {
+	t.log.Info("PeerDetached", logger.Ctx{
+		"client_id": clientID,
+	})
+}
@@ -0,0 +1,101 @@
+package servertransport
+
+import (
+	"encoding/json"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheusTracer is a MetadataTracer that exposes counters and a histogram
+// for metadata event traffic.
+type prometheusTracer struct {
+	eventsTotal    *prometheus.CounterVec
+	eventBytes     prometheus.Histogram
+	dropsTotal     *prometheus.CounterVec
+	errorsTotal    *prometheus.CounterVec
+	peersConnected prometheus.Gauge
+}
+
+// NewPrometheusTracer returns a MetadataTracer and registers its metrics on
+// reg. Pass prometheus.DefaultRegisterer to use the global registry.
+func NewPrometheusTracer(reg prometheus.Registerer) MetadataTracer {
+	t := &prometheusTracer{
+		eventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "peercalls",
+			Subsystem: "metadata",
+			Name:      "events_total",
+			Help:      "Total number of metadata events, by type and direction.",
+		}, []string{"type", "direction"}),
+		eventBytes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "peercalls",
+			Subsystem: "metadata",
+			Name:      "event_bytes",
+			Help:      "Approximate size in bytes of a single metadata event.",
+			Buckets:   prometheus.ExponentialBuckets(32, 2, 10),
+		}),
+		dropsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "peercalls",
+			Subsystem: "metadata",
+			Name:      "drops_total",
+			Help:      "Total number of metadata events dropped, by reason.",
+		}, []string{"reason"}),
+		errorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "peercalls",
+			Subsystem: "metadata",
+			Name:      "errors_total",
+			Help:      "Total number of metadata conn errors, by direction.",
+		}, []string{"direction"}),
+		peersConnected: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "peercalls",
+			Subsystem: "metadata",
+			Name:      "peers_connected",
+			Help:      "Number of currently attached metadata transport peers.",
+		}),
+	}
+
+	reg.MustRegister(t.eventsTotal, t.eventBytes, t.dropsTotal, t.errorsTotal, t.peersConnected)
+
+	return t
+}
+
+func (t *prometheusTracer) EventSent(_ string, ev metadataEvent) {
+	t.eventsTotal.WithLabelValues(ev.Type.String(), "sent").Inc()
+	t.eventBytes.Observe(float64(approxEventSize(ev)))
+}
+
+func (t *prometheusTracer) EventReceived(_ string, ev metadataEvent) {
+	t.eventsTotal.WithLabelValues(ev.Type.String(), "received").Inc()
+	t.eventBytes.Observe(float64(approxEventSize(ev)))
+}
+
+func (t *prometheusTracer) EventDropped(_ string, _ metadataEvent, reason string) {
+	t.dropsTotal.WithLabelValues(reason).Inc()
+}
+
+func (t *prometheusTracer) ReadError(_ string, _ error) {
+	t.errorsTotal.WithLabelValues("read").Inc()
+}
+
+func (t *prometheusTracer) WriteError(_ string, _ error) {
+	t.errorsTotal.WithLabelValues("write").Inc()
+}
+
+func (t *prometheusTracer) PeerAttached(_ string) {
+	t.peersConnected.Inc()
+}
+
+func (t *prometheusTracer) PeerDetached(_ string) {
+	t.peersConnected.Dec()
+}
+
+// approxEventSize estimates the wire size of ev by re-encoding it as JSON.
+// It does not use the transport's configured codec, so the numbers reported
+// here are an approximation of the actual wire size, not an exact count.
+func approxEventSize(ev metadataEvent) int {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return 0
+	}
+
+	return len(b)
+}
@@ -1,9 +1,10 @@
 package servertransport
 
 import (
-	"encoding/json"
 	"io"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/peer-calls/peer-calls/server/logger"
@@ -11,17 +12,36 @@ import (
 	"github.com/pion/webrtc/v3"
 )
 
+// pliDebounce is the minimum time between two PLI events sent for the same
+// track, so that several downstream viewers losing a frame around the same
+// time don't storm the upstream peer with keyframe requests.
+const pliDebounce = 500 * time.Millisecond
+
 type MetadataTransport struct {
 	clientID string
 	conn     io.ReadWriteCloser
+	codec    MetadataCodec
+	tracer   MetadataTracer
 	log      logger.Logger
 
 	localTracks  map[transport.TrackID]transport.TrackInfo
 	remoteTracks map[transport.TrackID]transport.TrackInfo
-	mu           *sync.RWMutex
 
-	trackEventsCh chan transport.TrackEvent
-	writeCh       chan metadataEvent
+	// ssrcPayloadType and payloadTypeTracks let the RTP transport classify
+	// incoming packets by payload type even when a remote SSRC dynamically
+	// switches codecs mid-stream.
+	ssrcPayloadType   map[uint32]uint8
+	payloadTypeTracks map[uint8]transport.TrackID
+
+	mu *sync.RWMutex
+
+	lastPLI map[transport.TrackID]time.Time
+	pliMu   *sync.Mutex
+
+	trackEventsCh    chan transport.TrackEvent
+	feedbackEventsCh chan transport.FeedbackEvent
+	pongCh           chan uint64
+	writeCh          chan metadataEvent
 
 	closeWriteLoop  chan struct{}
 	writeLoopClosed chan struct{}
@@ -30,33 +50,108 @@ type MetadataTransport struct {
 
 var _ transport.MetadataTransport = &MetadataTransport{}
 
-func NewMetadataTransport(log logger.Logger, conn io.ReadWriteCloser, clientID string) *MetadataTransport {
+// MetadataTransportOption configures optional behavior of a
+// MetadataTransport. Use it to override defaults passed to
+// NewMetadataTransport.
+type MetadataTransportOption func(*MetadataTransport)
+
+// WithCodec overrides the MetadataCodec used to frame events on the wire.
+// The default is a datagram codec, which matches the historical behavior of
+// MetadataTransport and is only safe to use over packet-oriented conns. Pass
+// NewLengthPrefixedCodec for stream-oriented conns such as TCP or muxed
+// streams.
+func WithCodec(codec MetadataCodec) MetadataTransportOption {
+	return func(t *MetadataTransport) {
+		t.codec = codec
+	}
+}
+
+// WithTracer overrides the MetadataTracer used to observe transport events.
+// The default is a no-op tracer.
+func WithTracer(tracer MetadataTracer) MetadataTransportOption {
+	return func(t *MetadataTransport) {
+		t.tracer = tracer
+	}
+}
+
+func NewMetadataTransport(
+	log logger.Logger, conn io.ReadWriteCloser, clientID string, opts ...MetadataTransportOption,
+) *MetadataTransport {
 	log = log.WithNamespaceAppended("metadata_transport")
 
 	t := &MetadataTransport{
 		clientID:     clientID,
 		log:          log,
 		conn:         conn,
+		codec:        NewDatagramCodec(ReceiveMTU),
+		tracer:       noopTracer{},
 		localTracks:  map[transport.TrackID]transport.TrackInfo{},
 		remoteTracks: map[transport.TrackID]transport.TrackInfo{},
-		mu:           &sync.RWMutex{},
 
-		trackEventsCh: make(chan transport.TrackEvent),
-		writeCh:       make(chan metadataEvent),
+		ssrcPayloadType:   map[uint32]uint8{},
+		payloadTypeTracks: map[uint8]transport.TrackID{},
+
+		mu: &sync.RWMutex{},
+
+		lastPLI: map[transport.TrackID]time.Time{},
+		pliMu:   &sync.Mutex{},
+
+		trackEventsCh:    make(chan transport.TrackEvent),
+		feedbackEventsCh: make(chan transport.FeedbackEvent),
+		pongCh:           make(chan uint64),
+		writeCh:          make(chan metadataEvent),
 
 		closeWriteLoop:  make(chan struct{}),
 		writeLoopClosed: make(chan struct{}),
 		readLoopClosed:  make(chan struct{}),
 	}
 
+	for _, opt := range opts {
+		opt(t)
+	}
+
 	log.Trace("NewMetadataTransport", nil)
 
+	t.tracer.PeerAttached(clientID)
+
 	go t.startReadLoop()
 	go t.startWriteLoop()
+	go t.sendInit()
 
 	return t
 }
 
+// sendInit announces this side's protocol version right after the transport
+// is created, so a remote running a newer/older version can be told apart
+// from a remote that never speaks up (e.g. one using the legacy framing from
+// before InitEvent existed).
+func (t *MetadataTransport) sendInit() {
+	err := t.sendMetadataEvent(metadataEvent{
+		Type: metadataEventTypeInit,
+		Init: &initEventJSON{
+			ClientID:        t.clientID,
+			ProtocolVersion: metadataProtocolVersion,
+		},
+	})
+	if err != nil {
+		t.log.Error("sendInit", errors.Trace(err), nil)
+	}
+}
+
+// sendPong answers a Ping carrying nonce. It is dispatched with go from the
+// read loop, the same way sendInit is, so a write loop stalled on
+// backpressure cannot wedge the read loop and starve it of the very Pongs
+// PeerManager needs for liveness.
+func (t *MetadataTransport) sendPong(nonce uint64) {
+	err := t.sendMetadataEvent(metadataEvent{
+		Type: metadataEventTypePong,
+		Ping: &pingEventJSON{Nonce: nonce},
+	})
+	if err != nil {
+		t.log.Error("sendPong", errors.Trace(err), nil)
+	}
+}
+
 func (t *MetadataTransport) newServerTrack(trackInfo trackInfoJSON) *ServerTrack {
 	return &ServerTrack{
 		SimpleTrack: trackInfo.Track,
@@ -109,12 +204,7 @@ func (t *MetadataTransport) startWriteLoop() {
 			"metadata_event": event.Type,
 		})
 
-		b, err := json.Marshal(event)
-		if err != nil {
-			return errors.Trace(err)
-		}
-
-		_, err = t.conn.Write(b)
+		err := t.codec.Encode(t.conn, event)
 
 		return errors.Trace(err)
 	}
@@ -124,9 +214,12 @@ func (t *MetadataTransport) startWriteLoop() {
 		case event := <-t.writeCh:
 			if err := write(event); err != nil {
 				t.log.Error("Write", errors.Trace(err), nil)
+				t.tracer.WriteError(t.clientID, err)
 
 				continue
 			}
+
+			t.tracer.EventSent(t.clientID, event)
 		case <-t.closeWriteLoop:
 			return
 		}
@@ -136,26 +229,18 @@ func (t *MetadataTransport) startWriteLoop() {
 func (t *MetadataTransport) startReadLoop() {
 	defer func() {
 		close(t.trackEventsCh)
+		close(t.feedbackEventsCh)
+		close(t.pongCh)
 		close(t.readLoopClosed)
 
 		t.log.Trace("Read closed", nil)
 	}()
 
-	buf := make([]byte, ReceiveMTU)
-
 	for {
-		i, err := t.conn.Read(buf)
+		event, err := t.codec.Decode(t.conn)
 		if err != nil {
 			t.log.Error("Read", errors.Trace(err), nil)
-
-			return
-		}
-
-		var event metadataEvent
-
-		err = json.Unmarshal(buf[:i], &event)
-		if err != nil {
-			t.log.Error("Unmarshal", err, nil)
+			t.tracer.ReadError(t.clientID, err)
 
 			return
 		}
@@ -164,12 +249,15 @@ func (t *MetadataTransport) startReadLoop() {
 			"metadata_event": event.Type,
 		})
 
+		t.tracer.EventReceived(t.clientID, event)
+
 		switch event.Type {
 		case metadataEventTypeTrack:
 			trackEvent := event.Track.trackEvent(t.clientID)
 			trackEvent.TrackWithMID.Track = t.newServerTrack(event.Track.TrackInfo)
 
 			skipEvent := false
+			codecChanged := false
 
 			switch trackEvent.Type {
 			case transport.TrackEventTypeAdd:
@@ -179,25 +267,150 @@ func (t *MetadataTransport) startReadLoop() {
 				// already been received.
 				_, skipEvent = t.remoteTracks[trackID]
 				t.remoteTracks[trackID] = trackEvent.TrackWithMID
+
+				if fields, ok := trackEvent.TrackWithMID.Track.(trackFields); ok {
+					codecChanged = t.updatePayloadType(fields.SSRC(), trackEvent.TrackWithMID.Codec.PayloadType, trackID)
+				}
+
 				t.mu.Unlock()
 			case transport.TrackEventTypeRemove:
+				trackID := trackEvent.TrackWithMID.Track.UniqueID()
+
 				t.mu.Lock()
-				delete(t.remoteTracks, trackEvent.TrackWithMID.Track.UniqueID())
+				delete(t.remoteTracks, trackID)
+
+				if fields, ok := trackEvent.TrackWithMID.Track.(trackFields); ok {
+					t.forgetPayloadType(fields.SSRC())
+				}
+
 				t.mu.Unlock()
+
+				t.pliMu.Lock()
+				delete(t.lastPLI, trackID)
+				t.pliMu.Unlock()
 			case transport.TrackEventTypeSub:
 			case transport.TrackEventTypeUnsub:
 			}
 
-			if !skipEvent {
+			if skipEvent {
+				t.tracer.EventDropped(t.clientID, event, "duplicate-add")
+			} else {
 				select {
 				case t.trackEventsCh <- trackEvent:
 				case <-t.writeLoopClosed:
+					t.tracer.EventDropped(t.clientID, event, "closed")
+				}
+			}
+
+			if codecChanged {
+				codecChangedEvent := trackEvent
+				codecChangedEvent.Type = transport.TrackEventTypeCodecChanged
+
+				select {
+				case t.trackEventsCh <- codecChangedEvent:
+				case <-t.writeLoopClosed:
+					t.tracer.EventDropped(t.clientID, event, "closed")
 				}
 			}
+		case metadataEventTypeInit:
+			t.log.Info("Init", logger.Ctx{
+				"client_id":        t.clientID,
+				"protocol_version": event.Init.ProtocolVersion,
+			})
+
+			if event.Init.ProtocolVersion != metadataProtocolVersion {
+				t.log.Error("Init: protocol version mismatch", errors.Errorf(
+					"remote version %d != expected version %d", event.Init.ProtocolVersion, metadataProtocolVersion,
+				), logger.Ctx{
+					"client_id": t.clientID,
+				})
+			}
+		case metadataEventTypeBye:
+			t.log.Info("Bye", logger.Ctx{
+				"client_id": t.clientID,
+			})
+		case metadataEventTypePLI, metadataEventTypeFIR, metadataEventTypeREMB,
+			metadataEventTypeNACK, metadataEventTypeSenderReport:
+			feedbackEvent := event.Feedback.feedbackEvent(t.clientID, event.Type)
+
+			select {
+			case t.feedbackEventsCh <- feedbackEvent:
+			case <-t.writeLoopClosed:
+				t.tracer.EventDropped(t.clientID, event, "closed")
+			}
+		case metadataEventTypePing:
+			go t.sendPong(event.Ping.Nonce)
+		case metadataEventTypePong:
+			select {
+			case t.pongCh <- event.Ping.Nonce:
+			case <-t.writeLoopClosed:
+				t.tracer.EventDropped(t.clientID, event, "closed")
+			}
 		}
 	}
 }
 
+// PongChannel delivers the nonce of every Pong received in answer to a
+// SendPing call, so a caller such as PeerManager can track liveness.
+func (t *MetadataTransport) PongChannel() <-chan uint64 {
+	return t.pongCh
+}
+
+// SendPing sends a Ping carrying nonce. The remote MetadataTransport answers
+// with a Pong echoing the same nonce, delivered on PongChannel.
+func (t *MetadataTransport) SendPing(nonce uint64) error {
+	err := t.sendMetadataEvent(metadataEvent{
+		Type: metadataEventTypePing,
+		Ping: &pingEventJSON{Nonce: nonce},
+	})
+
+	return errors.Annotatef(err, "sendPing: write")
+}
+
+// FeedbackChannel returns feedback events (PLI, FIR, REMB, NACK and sender
+// reports) received from the remote side, so the media transport can
+// translate them back into real RTCP packets for the originating client.
+func (t *MetadataTransport) FeedbackChannel() <-chan transport.FeedbackEvent {
+	return t.feedbackEventsCh
+}
+
+// SendFeedback sends an RTCP-style feedback message for trackID to the
+// remote side. PLI feedback is debounced to at most once per pliDebounce
+// interval per track.
+func (t *MetadataTransport) SendFeedback(trackID transport.TrackID, fb transport.Feedback) error {
+	if fb.Type == transport.FeedbackTypePLI && t.debouncePLI(trackID) {
+		t.tracer.EventDropped(t.clientID, metadataEvent{Type: metadataEventTypePLI}, "pli-debounce")
+
+		return nil
+	}
+
+	feedback := newFeedbackEventJSON(trackID, fb)
+
+	err := t.sendMetadataEvent(metadataEvent{
+		Type:     feedbackMetadataEventType(fb),
+		Feedback: &feedback,
+	})
+
+	return errors.Annotatef(err, "sendFeedback: write")
+}
+
+// debouncePLI reports whether a PLI for trackID was already sent within the
+// last pliDebounce interval, recording this one as the latest if not.
+func (t *MetadataTransport) debouncePLI(trackID transport.TrackID) bool {
+	t.pliMu.Lock()
+	defer t.pliMu.Unlock()
+
+	now := time.Now()
+
+	if last, ok := t.lastPLI[trackID]; ok && now.Sub(last) < pliDebounce {
+		return true
+	}
+
+	t.lastPLI[trackID] = now
+
+	return false
+}
+
 func (t *MetadataTransport) TrackEventsChannel() <-chan transport.TrackEvent {
 	return t.trackEventsCh
 }
@@ -266,18 +479,64 @@ func (t *MetadataTransport) sendMetadataEvent(event metadataEvent) error {
 	case t.writeCh <- event:
 		return nil
 	case <-t.writeLoopClosed:
+		t.tracer.EventDropped(t.clientID, event, "closed")
+
 		return errors.Annotatef(io.ErrClosedPipe, "sendMetadataEvent: write")
 	}
 }
 
+// updatePayloadType records the current payload type used by ssrc, and
+// reports whether it differs from a previously known payload type for the
+// same ssrc. Callers must hold t.mu.
+func (t *MetadataTransport) updatePayloadType(ssrc uint32, payloadType uint8, trackID transport.TrackID) bool {
+	prevPayloadType, known := t.ssrcPayloadType[ssrc]
+
+	t.ssrcPayloadType[ssrc] = payloadType
+	t.payloadTypeTracks[payloadType] = trackID
+
+	return known && prevPayloadType != payloadType
+}
+
+// forgetPayloadType removes ssrc's payload-type mapping, so that a removed
+// track's SSRC and payload type don't stay in ssrcPayloadType/
+// payloadTypeTracks forever. Callers must hold t.mu.
+func (t *MetadataTransport) forgetPayloadType(ssrc uint32) {
+	payloadType, ok := t.ssrcPayloadType[ssrc]
+	if !ok {
+		return
+	}
+
+	delete(t.ssrcPayloadType, ssrc)
+	delete(t.payloadTypeTracks, payloadType)
+}
+
+// TrackByPayloadType returns the remote track currently using payloadType,
+// as last reported by the sending side's TrackInfo.Codec. This lets the RTP
+// transport classify incoming packets even across a dynamic payload-type
+// remap.
+func (t *MetadataTransport) TrackByPayloadType(payloadType uint8) (transport.TrackInfo, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	trackID, ok := t.payloadTypeTracks[payloadType]
+	if !ok {
+		return transport.TrackInfo{}, false
+	}
+
+	trackInfo, ok := t.remoteTracks[trackID]
+
+	return trackInfo, ok
+}
+
 func (t *MetadataTransport) getCodecType(payloadType uint8) webrtc.RTPCodecType {
-	// // TODO These values are dynamic and are only valid when they are set in
-	// // media engine _and_ when we initiate peer connections.
-	// if payloadType == webrtc.DefaultPayloadTypeVP {
-	// 	return webrtc.RTPCodecTypeVideo
-	// }
+	trackInfo, ok := t.TrackByPayloadType(payloadType)
+	if !ok {
+		return webrtc.RTPCodecTypeAudio
+	}
 
-	// FIXME
+	if strings.HasPrefix(strings.ToLower(trackInfo.Codec.MimeType), "video/") {
+		return webrtc.RTPCodecTypeVideo
+	}
 
 	return webrtc.RTPCodecTypeAudio
 }
@@ -305,7 +564,18 @@ func (t *MetadataTransport) RemoveTrack(trackID transport.TrackID) error {
 	return t.sendTrackEvent(trackEvent)
 }
 
+// Done is closed once the read loop has stopped, which happens as soon as
+// the conn fails or Close is called. PeerManager watches it to tell a dead
+// conn apart from one that is merely idle.
+func (t *MetadataTransport) Done() <-chan struct{} {
+	return t.readLoopClosed
+}
+
 func (t *MetadataTransport) Close() error {
+	if sendErr := t.sendMetadataEvent(metadataEvent{Type: metadataEventTypeBye, Bye: &byeEventJSON{}}); sendErr != nil {
+		t.log.Error("Close: send bye", errors.Trace(sendErr), nil)
+	}
+
 	err := t.conn.Close()
 
 	select {
@@ -316,5 +586,7 @@ func (t *MetadataTransport) Close() error {
 
 	<-t.readLoopClosed
 
+	t.tracer.PeerDetached(t.clientID)
+
 	return errors.Trace(err)
 }
@@ -0,0 +1,1278 @@
+// Package proto is a hand-written protobuf-wire-format encoder/decoder for
+// the schema in metadata.proto. There is no codegen step wired up for this
+// package; if you add a field or message to metadata.proto, make the
+// matching edit here too.
+package proto
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/juju/errors"
+)
+
+type TrackKind int32
+
+const (
+	TrackKind_TRACK_KIND_UNKNOWN TrackKind = 0
+	TrackKind_TRACK_KIND_AUDIO   TrackKind = 1
+	TrackKind_TRACK_KIND_VIDEO   TrackKind = 2
+	TrackKind_TRACK_KIND_DATA    TrackKind = 3
+)
+
+var TrackKind_name = map[int32]string{
+	0: "TRACK_KIND_UNKNOWN",
+	1: "TRACK_KIND_AUDIO",
+	2: "TRACK_KIND_VIDEO",
+	3: "TRACK_KIND_DATA",
+}
+
+func (k TrackKind) String() string {
+	if name, ok := TrackKind_name[int32(k)]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("TrackKind(%d)", k)
+}
+
+type TrackEventType int32
+
+const (
+	TrackEventType_TRACK_EVENT_TYPE_UNKNOWN TrackEventType = 0
+	TrackEventType_TRACK_EVENT_TYPE_ADD     TrackEventType = 1
+	TrackEventType_TRACK_EVENT_TYPE_REMOVE  TrackEventType = 2
+	TrackEventType_TRACK_EVENT_TYPE_SUB     TrackEventType = 3
+	TrackEventType_TRACK_EVENT_TYPE_UNSUB   TrackEventType = 4
+)
+
+var TrackEventType_name = map[int32]string{
+	0: "TRACK_EVENT_TYPE_UNKNOWN",
+	1: "TRACK_EVENT_TYPE_ADD",
+	2: "TRACK_EVENT_TYPE_REMOVE",
+	3: "TRACK_EVENT_TYPE_SUB",
+	4: "TRACK_EVENT_TYPE_UNSUB",
+}
+
+func (t TrackEventType) String() string {
+	if name, ok := TrackEventType_name[int32(t)]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("TrackEventType(%d)", t)
+}
+
+type TrackInfo struct {
+	Id           string    `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Mid          string    `protobuf:"bytes,2,opt,name=mid,proto3" json:"mid,omitempty"`
+	Kind         TrackKind `protobuf:"varint,3,opt,name=kind,proto3,enum=servertransport.proto.TrackKind" json:"kind,omitempty"`
+	PayloadType  uint32    `protobuf:"varint,4,opt,name=payload_type,json=payloadType,proto3" json:"payload_type,omitempty"`
+	ClockRate    uint32    `protobuf:"varint,5,opt,name=clock_rate,json=clockRate,proto3" json:"clock_rate,omitempty"`
+	MimeType     string    `protobuf:"bytes,6,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	Rid          string    `protobuf:"bytes,7,opt,name=rid,proto3" json:"rid,omitempty"`
+	StreamId     string    `protobuf:"bytes,8,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	Ssrc         uint32    `protobuf:"varint,9,opt,name=ssrc,proto3" json:"ssrc,omitempty"`
+	Channels     uint32    `protobuf:"varint,10,opt,name=channels,proto3" json:"channels,omitempty"`
+	SdpFmtpLine  string    `protobuf:"bytes,11,opt,name=sdp_fmtp_line,json=sdpFmtpLine,proto3" json:"sdp_fmtp_line,omitempty"`
+	RtcpFeedback []string  `protobuf:"bytes,12,rep,name=rtcp_feedback,json=rtcpFeedback,proto3" json:"rtcp_feedback,omitempty"`
+}
+
+type TrackEvent struct {
+	ClientId  string         `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Type      TrackEventType `protobuf:"varint,2,opt,name=type,proto3,enum=servertransport.proto.TrackEventType" json:"type,omitempty"`
+	TrackInfo *TrackInfo     `protobuf:"bytes,3,opt,name=track_info,json=trackInfo,proto3" json:"track_info,omitempty"`
+}
+
+type InitEvent struct {
+	ClientId        string   `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	ProtocolVersion uint32   `protobuf:"varint,2,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	Capabilities    []string `protobuf:"bytes,3,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+}
+
+type ByeEvent struct {
+}
+
+type NACKPair struct {
+	PacketId uint32 `protobuf:"varint,1,opt,name=packet_id,json=packetId,proto3" json:"packet_id,omitempty"`
+	LostMask uint32 `protobuf:"varint,2,opt,name=lost_mask,json=lostMask,proto3" json:"lost_mask,omitempty"`
+}
+
+type SenderReport struct {
+	NtpTime     uint64 `protobuf:"varint,1,opt,name=ntp_time,json=ntpTime,proto3" json:"ntp_time,omitempty"`
+	RtpTime     uint32 `protobuf:"varint,2,opt,name=rtp_time,json=rtpTime,proto3" json:"rtp_time,omitempty"`
+	PacketCount uint32 `protobuf:"varint,3,opt,name=packet_count,json=packetCount,proto3" json:"packet_count,omitempty"`
+	OctetCount  uint32 `protobuf:"varint,4,opt,name=octet_count,json=octetCount,proto3" json:"octet_count,omitempty"`
+}
+
+type FeedbackEvent struct {
+	TrackId      string        `protobuf:"bytes,1,opt,name=track_id,json=trackId,proto3" json:"track_id,omitempty"`
+	Ssrc         uint32        `protobuf:"varint,2,opt,name=ssrc,proto3" json:"ssrc,omitempty"`
+	Bitrate      uint64        `protobuf:"varint,3,opt,name=bitrate,proto3" json:"bitrate,omitempty"`
+	FirSeqno     uint32        `protobuf:"varint,4,opt,name=fir_seqno,json=firSeqno,proto3" json:"fir_seqno,omitempty"`
+	Nacks        []*NACKPair   `protobuf:"bytes,5,rep,name=nacks,proto3" json:"nacks,omitempty"`
+	SenderReport *SenderReport `protobuf:"bytes,6,opt,name=sender_report,json=senderReport,proto3" json:"sender_report,omitempty"`
+}
+
+type PingEvent struct {
+	Nonce uint64 `protobuf:"varint,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+// isEvent_Payload is implemented by every Event_* wrapper type, mirroring the
+// oneof pattern emitted by protoc-gen-go.
+type isEvent_Payload interface {
+	isEvent_Payload()
+}
+
+type Event_TrackEvent struct {
+	TrackEvent *TrackEvent `protobuf:"bytes,1,opt,name=track_event,json=trackEvent,proto3,oneof"`
+}
+
+type Event_InitEvent struct {
+	InitEvent *InitEvent `protobuf:"bytes,2,opt,name=init_event,json=initEvent,proto3,oneof"`
+}
+
+type Event_ByeEvent struct {
+	ByeEvent *ByeEvent `protobuf:"bytes,3,opt,name=bye_event,json=byeEvent,proto3,oneof"`
+}
+
+type Event_PliEvent struct {
+	PliEvent *FeedbackEvent `protobuf:"bytes,4,opt,name=pli_event,json=pliEvent,proto3,oneof"`
+}
+
+type Event_FirEvent struct {
+	FirEvent *FeedbackEvent `protobuf:"bytes,5,opt,name=fir_event,json=firEvent,proto3,oneof"`
+}
+
+type Event_RembEvent struct {
+	RembEvent *FeedbackEvent `protobuf:"bytes,6,opt,name=remb_event,json=rembEvent,proto3,oneof"`
+}
+
+type Event_NackEvent struct {
+	NackEvent *FeedbackEvent `protobuf:"bytes,7,opt,name=nack_event,json=nackEvent,proto3,oneof"`
+}
+
+type Event_SenderReportEvent struct {
+	SenderReportEvent *FeedbackEvent `protobuf:"bytes,8,opt,name=sender_report_event,json=senderReportEvent,proto3,oneof"`
+}
+
+type Event_PingEvent struct {
+	PingEvent *PingEvent `protobuf:"bytes,9,opt,name=ping_event,json=pingEvent,proto3,oneof"`
+}
+
+type Event_PongEvent struct {
+	PongEvent *PingEvent `protobuf:"bytes,10,opt,name=pong_event,json=pongEvent,proto3,oneof"`
+}
+
+func (*Event_TrackEvent) isEvent_Payload()        {}
+func (*Event_InitEvent) isEvent_Payload()         {}
+func (*Event_ByeEvent) isEvent_Payload()          {}
+func (*Event_PliEvent) isEvent_Payload()          {}
+func (*Event_FirEvent) isEvent_Payload()          {}
+func (*Event_RembEvent) isEvent_Payload()         {}
+func (*Event_NackEvent) isEvent_Payload()         {}
+func (*Event_SenderReportEvent) isEvent_Payload() {}
+func (*Event_PingEvent) isEvent_Payload()         {}
+func (*Event_PongEvent) isEvent_Payload()         {}
+
+type Event struct {
+	// Payload is one of the Event_* wrapper types declared above.
+	Payload isEvent_Payload `protobuf_oneof:"payload"`
+}
+
+func (m *Event) GetTrackEvent() *TrackEvent {
+	if x, ok := m.GetPayload().(*Event_TrackEvent); ok {
+		return x.TrackEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetInitEvent() *InitEvent {
+	if x, ok := m.GetPayload().(*Event_InitEvent); ok {
+		return x.InitEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetByeEvent() *ByeEvent {
+	if x, ok := m.GetPayload().(*Event_ByeEvent); ok {
+		return x.ByeEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetPliEvent() *FeedbackEvent {
+	if x, ok := m.GetPayload().(*Event_PliEvent); ok {
+		return x.PliEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetFirEvent() *FeedbackEvent {
+	if x, ok := m.GetPayload().(*Event_FirEvent); ok {
+		return x.FirEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetRembEvent() *FeedbackEvent {
+	if x, ok := m.GetPayload().(*Event_RembEvent); ok {
+		return x.RembEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetNackEvent() *FeedbackEvent {
+	if x, ok := m.GetPayload().(*Event_NackEvent); ok {
+		return x.NackEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetSenderReportEvent() *FeedbackEvent {
+	if x, ok := m.GetPayload().(*Event_SenderReportEvent); ok {
+		return x.SenderReportEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetPingEvent() *PingEvent {
+	if x, ok := m.GetPayload().(*Event_PingEvent); ok {
+		return x.PingEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetPongEvent() *PingEvent {
+	if x, ok := m.GetPayload().(*Event_PongEvent); ok {
+		return x.PongEvent
+	}
+
+	return nil
+}
+
+func (m *Event) GetPayload() isEvent_Payload {
+	if m != nil {
+		return m.Payload
+	}
+
+	return nil
+}
+
+// Marshal encodes the Event to the protobuf wire format.
+func (m *Event) Marshal() ([]byte, error) {
+	size := m.Size()
+	dAtA := make([]byte, size)
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *Event) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	switch p := m.Payload.(type) {
+	case *Event_TrackEvent:
+		if p.TrackEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 1, p.TrackEvent)
+		}
+	case *Event_InitEvent:
+		if p.InitEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 2, p.InitEvent)
+		}
+	case *Event_ByeEvent:
+		if p.ByeEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 3, p.ByeEvent)
+		}
+	case *Event_PliEvent:
+		if p.PliEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 4, p.PliEvent)
+		}
+	case *Event_FirEvent:
+		if p.FirEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 5, p.FirEvent)
+		}
+	case *Event_RembEvent:
+		if p.RembEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 6, p.RembEvent)
+		}
+	case *Event_NackEvent:
+		if p.NackEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 7, p.NackEvent)
+		}
+	case *Event_SenderReportEvent:
+		if p.SenderReportEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 8, p.SenderReportEvent)
+		}
+	case *Event_PingEvent:
+		if p.PingEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 9, p.PingEvent)
+		}
+	case *Event_PongEvent:
+		if p.PongEvent != nil {
+			i += writeEmbeddedMessage(dAtA[i:], 10, p.PongEvent)
+		}
+	}
+
+	return i, nil
+}
+
+func (m *Event) Size() int {
+	switch p := m.Payload.(type) {
+	case *Event_TrackEvent:
+		if p.TrackEvent != nil {
+			return sizeEmbeddedMessage(1, p.TrackEvent)
+		}
+	case *Event_InitEvent:
+		if p.InitEvent != nil {
+			return sizeEmbeddedMessage(2, p.InitEvent)
+		}
+	case *Event_ByeEvent:
+		if p.ByeEvent != nil {
+			return sizeEmbeddedMessage(3, p.ByeEvent)
+		}
+	case *Event_PliEvent:
+		if p.PliEvent != nil {
+			return sizeEmbeddedMessage(4, p.PliEvent)
+		}
+	case *Event_FirEvent:
+		if p.FirEvent != nil {
+			return sizeEmbeddedMessage(5, p.FirEvent)
+		}
+	case *Event_RembEvent:
+		if p.RembEvent != nil {
+			return sizeEmbeddedMessage(6, p.RembEvent)
+		}
+	case *Event_NackEvent:
+		if p.NackEvent != nil {
+			return sizeEmbeddedMessage(7, p.NackEvent)
+		}
+	case *Event_SenderReportEvent:
+		if p.SenderReportEvent != nil {
+			return sizeEmbeddedMessage(8, p.SenderReportEvent)
+		}
+	case *Event_PingEvent:
+		if p.PingEvent != nil {
+			return sizeEmbeddedMessage(9, p.PingEvent)
+		}
+	case *Event_PongEvent:
+		if p.PongEvent != nil {
+			return sizeEmbeddedMessage(10, p.PongEvent)
+		}
+	}
+
+	return 0
+}
+
+func (m *Event) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		if wireType != 2 {
+			return errors.Errorf("Event: unsupported wire type %d for field %d", wireType, fieldNum)
+		}
+
+		payload, n, err := readBytes(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			v := &TrackEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_TrackEvent{TrackEvent: v}
+		case 2:
+			v := &InitEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_InitEvent{InitEvent: v}
+		case 3:
+			v := &ByeEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_ByeEvent{ByeEvent: v}
+		case 4:
+			v := &FeedbackEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_PliEvent{PliEvent: v}
+		case 5:
+			v := &FeedbackEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_FirEvent{FirEvent: v}
+		case 6:
+			v := &FeedbackEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_RembEvent{RembEvent: v}
+		case 7:
+			v := &FeedbackEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_NackEvent{NackEvent: v}
+		case 8:
+			v := &FeedbackEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_SenderReportEvent{SenderReportEvent: v}
+		case 9:
+			v := &PingEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_PingEvent{PingEvent: v}
+		case 10:
+			v := &PingEvent{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Payload = &Event_PongEvent{PongEvent: v}
+		default:
+			return errors.Errorf("Event: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+func (m *TrackInfo) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *TrackInfo) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	i += writeString(dAtA[i:], 1, m.Id)
+	i += writeString(dAtA[i:], 2, m.Mid)
+	i += writeVarintField(dAtA[i:], 3, uint64(m.Kind))
+	i += writeVarintField(dAtA[i:], 4, uint64(m.PayloadType))
+	i += writeVarintField(dAtA[i:], 5, uint64(m.ClockRate))
+	i += writeString(dAtA[i:], 6, m.MimeType)
+	i += writeString(dAtA[i:], 7, m.Rid)
+	i += writeString(dAtA[i:], 8, m.StreamId)
+	i += writeVarintField(dAtA[i:], 9, uint64(m.Ssrc))
+	i += writeVarintField(dAtA[i:], 10, uint64(m.Channels))
+	i += writeString(dAtA[i:], 11, m.SdpFmtpLine)
+
+	for _, fb := range m.RtcpFeedback {
+		i += writeString(dAtA[i:], 12, fb)
+	}
+
+	return i, nil
+}
+
+func (m *TrackInfo) Size() int {
+	n := 0
+
+	n += sizeString(1, m.Id)
+	n += sizeString(2, m.Mid)
+	n += sizeVarintField(3, uint64(m.Kind))
+	n += sizeVarintField(4, uint64(m.PayloadType))
+	n += sizeVarintField(5, uint64(m.ClockRate))
+	n += sizeString(6, m.MimeType)
+	n += sizeString(7, m.Rid)
+	n += sizeString(8, m.StreamId)
+	n += sizeVarintField(9, uint64(m.Ssrc))
+	n += sizeVarintField(10, uint64(m.Channels))
+	n += sizeString(11, m.SdpFmtpLine)
+
+	for _, fb := range m.RtcpFeedback {
+		n += sizeString(12, fb)
+	}
+
+	return n
+}
+
+func (m *TrackInfo) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Id, i = s, i+n
+		case 2:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Mid, i = s, i+n
+		case 3:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Kind, i = TrackKind(v), i+n
+		case 4:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.PayloadType, i = uint32(v), i+n
+		case 5:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.ClockRate, i = uint32(v), i+n
+		case 6:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.MimeType, i = s, i+n
+		case 7:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Rid, i = s, i+n
+		case 8:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.StreamId, i = s, i+n
+		case 9:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Ssrc, i = uint32(v), i+n
+		case 10:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Channels, i = uint32(v), i+n
+		case 11:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.SdpFmtpLine, i = s, i+n
+		case 12:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.RtcpFeedback = append(m.RtcpFeedback, s)
+			i += n
+		default:
+			return errors.Errorf("TrackInfo: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+func (m *TrackEvent) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *TrackEvent) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	i += writeString(dAtA[i:], 1, m.ClientId)
+	i += writeVarintField(dAtA[i:], 2, uint64(m.Type))
+
+	if m.TrackInfo != nil {
+		i += writeEmbeddedMessage(dAtA[i:], 3, m.TrackInfo)
+	}
+
+	return i, nil
+}
+
+func (m *TrackEvent) Size() int {
+	n := 0
+
+	n += sizeString(1, m.ClientId)
+	n += sizeVarintField(2, uint64(m.Type))
+
+	if m.TrackInfo != nil {
+		n += sizeEmbeddedMessage(3, m.TrackInfo)
+	}
+
+	return n
+}
+
+func (m *TrackEvent) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.ClientId, i = s, i+n
+		case 2:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Type, i = TrackEventType(v), i+n
+		case 3:
+			payload, n, err := readBytes(dAtA[i:])
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			v := &TrackInfo{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.TrackInfo, i = v, i+n
+		default:
+			return errors.Errorf("TrackEvent: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+func (m *InitEvent) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *InitEvent) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	i += writeString(dAtA[i:], 1, m.ClientId)
+	i += writeVarintField(dAtA[i:], 2, uint64(m.ProtocolVersion))
+
+	for _, c := range m.Capabilities {
+		i += writeString(dAtA[i:], 3, c)
+	}
+
+	return i, nil
+}
+
+func (m *InitEvent) Size() int {
+	n := 0
+
+	n += sizeString(1, m.ClientId)
+	n += sizeVarintField(2, uint64(m.ProtocolVersion))
+
+	for _, c := range m.Capabilities {
+		n += sizeString(3, c)
+	}
+
+	return n
+}
+
+func (m *InitEvent) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.ClientId, i = s, i+n
+		case 2:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.ProtocolVersion, i = uint32(v), i+n
+		case 3:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Capabilities = append(m.Capabilities, s)
+			i += n
+		default:
+			return errors.Errorf("InitEvent: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+func (m *ByeEvent) Marshal() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (m *ByeEvent) MarshalTo(dAtA []byte) (int, error) {
+	return 0, nil
+}
+
+func (m *ByeEvent) Size() int {
+	return 0
+}
+
+func (m *ByeEvent) Unmarshal(dAtA []byte) error {
+	if len(dAtA) != 0 {
+		return errors.Errorf("ByeEvent: unexpected payload of %d bytes", len(dAtA))
+	}
+
+	return nil
+}
+
+func (m *NACKPair) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *NACKPair) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	i += writeVarintField(dAtA[i:], 1, uint64(m.PacketId))
+	i += writeVarintField(dAtA[i:], 2, uint64(m.LostMask))
+
+	return i, nil
+}
+
+func (m *NACKPair) Size() int {
+	n := 0
+
+	n += sizeVarintField(1, uint64(m.PacketId))
+	n += sizeVarintField(2, uint64(m.LostMask))
+
+	return n
+}
+
+func (m *NACKPair) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.PacketId, i = uint32(v), i+n
+		case 2:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.LostMask, i = uint32(v), i+n
+		default:
+			return errors.Errorf("NACKPair: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+func (m *SenderReport) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *SenderReport) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	i += writeVarintField(dAtA[i:], 1, m.NtpTime)
+	i += writeVarintField(dAtA[i:], 2, uint64(m.RtpTime))
+	i += writeVarintField(dAtA[i:], 3, uint64(m.PacketCount))
+	i += writeVarintField(dAtA[i:], 4, uint64(m.OctetCount))
+
+	return i, nil
+}
+
+func (m *SenderReport) Size() int {
+	n := 0
+
+	n += sizeVarintField(1, m.NtpTime)
+	n += sizeVarintField(2, uint64(m.RtpTime))
+	n += sizeVarintField(3, uint64(m.PacketCount))
+	n += sizeVarintField(4, uint64(m.OctetCount))
+
+	return n
+}
+
+func (m *SenderReport) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.NtpTime, i = v, i+n
+		case 2:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.RtpTime, i = uint32(v), i+n
+		case 3:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.PacketCount, i = uint32(v), i+n
+		case 4:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.OctetCount, i = uint32(v), i+n
+		default:
+			return errors.Errorf("SenderReport: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+func (m *FeedbackEvent) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *FeedbackEvent) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	i += writeString(dAtA[i:], 1, m.TrackId)
+	i += writeVarintField(dAtA[i:], 2, uint64(m.Ssrc))
+	i += writeVarintField(dAtA[i:], 3, m.Bitrate)
+	i += writeVarintField(dAtA[i:], 4, uint64(m.FirSeqno))
+
+	for _, nack := range m.Nacks {
+		i += writeEmbeddedMessage(dAtA[i:], 5, nack)
+	}
+
+	if m.SenderReport != nil {
+		i += writeEmbeddedMessage(dAtA[i:], 6, m.SenderReport)
+	}
+
+	return i, nil
+}
+
+func (m *FeedbackEvent) Size() int {
+	n := 0
+
+	n += sizeString(1, m.TrackId)
+	n += sizeVarintField(2, uint64(m.Ssrc))
+	n += sizeVarintField(3, m.Bitrate)
+	n += sizeVarintField(4, uint64(m.FirSeqno))
+
+	for _, nack := range m.Nacks {
+		n += sizeEmbeddedMessage(5, nack)
+	}
+
+	if m.SenderReport != nil {
+		n += sizeEmbeddedMessage(6, m.SenderReport)
+	}
+
+	return n
+}
+
+func (m *FeedbackEvent) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			s, n, err := readString(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.TrackId, i = s, i+n
+		case 2:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Ssrc, i = uint32(v), i+n
+		case 3:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Bitrate, i = v, i+n
+		case 4:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.FirSeqno, i = uint32(v), i+n
+		case 5:
+			payload, n, err := readBytes(dAtA[i:])
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			v := &NACKPair{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Nacks = append(m.Nacks, v)
+			i += n
+		case 6:
+			payload, n, err := readBytes(dAtA[i:])
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			v := &SenderReport{}
+			if err := v.Unmarshal(payload); err != nil {
+				return errors.Trace(err)
+			}
+
+			m.SenderReport, i = v, i+n
+		default:
+			return errors.Errorf("FeedbackEvent: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+func (m *PingEvent) Marshal() ([]byte, error) {
+	dAtA := make([]byte, m.Size())
+
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return dAtA[:n], nil
+}
+
+func (m *PingEvent) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+
+	i += writeVarintField(dAtA[i:], 1, m.Nonce)
+
+	return i, nil
+}
+
+func (m *PingEvent) Size() int {
+	n := 0
+
+	n += sizeVarintField(1, m.Nonce)
+
+	return n
+}
+
+func (m *PingEvent) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		fieldNum, wireType, n, err := decodeTag(dAtA[i:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		i += n
+
+		switch fieldNum {
+		case 1:
+			v, n, err := readVarint(dAtA[i:], wireType)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			m.Nonce, i = v, i+n
+		default:
+			return errors.Errorf("PingEvent: unknown field %d", fieldNum)
+		}
+	}
+
+	return nil
+}
+
+// marshaler is implemented by every generated message and lets
+// writeEmbeddedMessage/sizeEmbeddedMessage stay generic.
+type marshaler interface {
+	MarshalTo(dAtA []byte) (int, error)
+	Size() int
+}
+
+func encodeVarint(dAtA []byte, v uint64) int {
+	i := 0
+
+	for v >= 0x80 {
+		dAtA[i] = byte(v) | 0x80
+		v >>= 7
+		i++
+	}
+
+	dAtA[i] = byte(v)
+
+	return i + 1
+}
+
+func sovMetadata(v uint64) int {
+	n := 1
+
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+
+	return n
+}
+
+func writeVarintField(dAtA []byte, fieldNum int, v uint64) int {
+	if v == 0 {
+		return 0
+	}
+
+	i := encodeVarint(dAtA, uint64(fieldNum<<3))
+
+	return i + encodeVarint(dAtA[i:], v)
+}
+
+func sizeVarintField(fieldNum int, v uint64) int {
+	if v == 0 {
+		return 0
+	}
+
+	return sovMetadata(uint64(fieldNum<<3)) + sovMetadata(v)
+}
+
+func writeString(dAtA []byte, fieldNum int, s string) int {
+	if s == "" {
+		return 0
+	}
+
+	i := encodeVarint(dAtA, uint64(fieldNum<<3|2))
+	i += encodeVarint(dAtA[i:], uint64(len(s)))
+	i += copy(dAtA[i:], s)
+
+	return i
+}
+
+func sizeString(fieldNum int, s string) int {
+	if s == "" {
+		return 0
+	}
+
+	return sovMetadata(uint64(fieldNum<<3|2)) + sovMetadata(uint64(len(s))) + len(s)
+}
+
+func writeEmbeddedMessage(dAtA []byte, fieldNum int, m marshaler) int {
+	size := m.Size()
+
+	i := encodeVarint(dAtA, uint64(fieldNum<<3|2))
+	i += encodeVarint(dAtA[i:], uint64(size))
+
+	n, _ := m.MarshalTo(dAtA[i:])
+
+	return i + n
+}
+
+func sizeEmbeddedMessage(fieldNum int, m marshaler) int {
+	size := m.Size()
+
+	return sovMetadata(uint64(fieldNum<<3|2)) + sovMetadata(uint64(size)) + size
+}
+
+// decodeTag reads a field tag and returns the field number, wire type, and
+// the number of bytes consumed.
+func decodeTag(dAtA []byte) (fieldNum int, wireType int, n int, err error) {
+	v, n, err := readRawVarint(dAtA)
+	if err != nil {
+		return 0, 0, 0, errors.Trace(err)
+	}
+
+	return int(v >> 3), int(v & 0x7), n, nil
+}
+
+func readRawVarint(dAtA []byte) (uint64, int, error) {
+	var v uint64
+
+	for i := 0; i < len(dAtA); i++ {
+		b := dAtA[i]
+		v |= uint64(b&0x7f) << uint(7*i)
+
+		if b < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+
+	return 0, 0, errors.Annotatef(io.ErrUnexpectedEOF, "readRawVarint")
+}
+
+func readVarint(dAtA []byte, wireType int) (uint64, int, error) {
+	if wireType != 0 {
+		return 0, 0, errors.Errorf("readVarint: unexpected wire type %d", wireType)
+	}
+
+	return readRawVarint(dAtA)
+}
+
+func readBytes(dAtA []byte) ([]byte, int, error) {
+	size, n, err := readRawVarint(dAtA)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+
+	// size comes straight off the wire: a malicious or corrupt varint close to
+	// math.MaxUint64 would make int(size) wrap negative, and a naive
+	// n+int(size) could then wrap back into range and pass the end > len(dAtA)
+	// check below even though n > end. Reject size against the remaining
+	// buffer length first, entirely in uint64 arithmetic, before it ever
+	// touches a slice index.
+	if n < 0 || size > uint64(len(dAtA)-n) {
+		return nil, 0, errors.Annotatef(io.ErrUnexpectedEOF, "readBytes")
+	}
+
+	end := n + int(size)
+
+	return dAtA[n:end], end, nil
+}
+
+func readString(dAtA []byte, wireType int) (string, int, error) {
+	if wireType != 2 {
+		return "", 0, errors.Errorf("readString: unexpected wire type %d", wireType)
+	}
+
+	b, n, err := readBytes(dAtA)
+	if err != nil {
+		return "", 0, errors.Trace(err)
+	}
+
+	return string(b), n, nil
+}
@@ -0,0 +1,130 @@
+package proto
+
+import (
+	"reflect"
+	"testing"
+)
+
+// roundTrip marshals ev, unmarshals the result into a fresh Event, and
+// returns it, failing the test on any error.
+func roundTrip(t *testing.T, ev *Event) *Event {
+	t.Helper()
+
+	b, err := ev.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+
+	var got Event
+
+	if err := got.Unmarshal(b); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+
+	return &got
+}
+
+// TestEventRoundTrip guards against the oneof in Event falling out of sync
+// with Event.MarshalTo/Size/Unmarshal: every payload kind must marshal and
+// unmarshal back to an equal value, or one of those three methods is missing
+// or mis-numbering a oneof field.
+func TestEventRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *Event
+	}{
+		{
+			name: "TrackEvent",
+			in: &Event{Payload: &Event_TrackEvent{TrackEvent: &TrackEvent{
+				ClientId: "client-1",
+				Type:     TrackEventType_TRACK_EVENT_TYPE_ADD,
+				TrackInfo: &TrackInfo{
+					Id:          "track-1",
+					Mid:         "0",
+					Kind:        TrackKind_TRACK_KIND_VIDEO,
+					PayloadType: 96,
+					ClockRate:   90000,
+					MimeType:    "video/VP8",
+				},
+			}}},
+		},
+		{
+			name: "InitEvent",
+			in: &Event{Payload: &Event_InitEvent{InitEvent: &InitEvent{
+				ClientId:        "client-1",
+				ProtocolVersion: 1,
+				Capabilities:    []string{"codec-negotiation"},
+			}}},
+		},
+		{
+			name: "ByeEvent",
+			in:   &Event{Payload: &Event_ByeEvent{ByeEvent: &ByeEvent{}}},
+		},
+		{
+			name: "PliEvent",
+			in: &Event{Payload: &Event_PliEvent{PliEvent: &FeedbackEvent{
+				TrackId: "track-1",
+				Ssrc:    12345,
+			}}},
+		},
+		{
+			name: "FirEvent",
+			in: &Event{Payload: &Event_FirEvent{FirEvent: &FeedbackEvent{
+				TrackId:  "track-1",
+				Ssrc:     12345,
+				FirSeqno: 3,
+			}}},
+		},
+		{
+			name: "RembEvent",
+			in: &Event{Payload: &Event_RembEvent{RembEvent: &FeedbackEvent{
+				TrackId: "track-1",
+				Ssrc:    12345,
+				Bitrate: 1500000,
+			}}},
+		},
+		{
+			name: "NackEvent",
+			in: &Event{Payload: &Event_NackEvent{NackEvent: &FeedbackEvent{
+				TrackId: "track-1",
+				Ssrc:    12345,
+				Nacks: []*NACKPair{
+					{PacketId: 100, LostMask: 0x03},
+				},
+			}}},
+		},
+		{
+			name: "SenderReportEvent",
+			in: &Event{Payload: &Event_SenderReportEvent{SenderReportEvent: &FeedbackEvent{
+				TrackId: "track-1",
+				Ssrc:    12345,
+				SenderReport: &SenderReport{
+					NtpTime:     1234567890,
+					RtpTime:     42,
+					PacketCount: 10,
+					OctetCount:  1500,
+				},
+			}}},
+		},
+		{
+			name: "PingEvent",
+			in:   &Event{Payload: &Event_PingEvent{PingEvent: &PingEvent{Nonce: 7}}},
+		},
+		{
+			name: "PongEvent",
+			in:   &Event{Payload: &Event_PongEvent{PongEvent: &PingEvent{Nonce: 7}}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundTrip(t, tt.in)
+
+			if !reflect.DeepEqual(tt.in, got) {
+				t.Fatalf("round trip mismatch:\n  in:  %+v\n  out: %+v", tt.in, got)
+			}
+		})
+	}
+}